@@ -5,36 +5,56 @@
 package accesskit
 
 import (
-	"fmt"
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
-	"strings"
+	"time"
 )
 
 // NewDb 建立连接
 //
 //	conf 配置
 //	tablePrefix 数据表名的前缀，可以为空
+//
+// 如果配置了 Replicas ，则 SELECT 走从库，写操作和事务走主库，参考 UsePrimary
+//
+// 默认注册 ScopePlugin ，处理租户、审计字段与软删除；带租户字段的表需要在 context 中设置租户，参考 WithTenant
+//
+// 如果配置了 PingAttempts ，则会检测连接，失败时返回错误
 func NewDb(conf DbConfig, tablePrefix string) (*gorm.DB, error) {
 	c := &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			TablePrefix:   tablePrefix,
 			SingularTable: true,
 		},
+		// 由 pingDb 负责检测与重试
+		DisableAutomaticPing: conf.PingAttempts > 0,
+		Logger:               newDbLoggerByConfig(conf),
+	}
+	dialector, err := openDialector(conf.Dialect, conf.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, c)
+	if err != nil {
+		return nil, err
+	}
+	if err = useReplicas(db, conf); err != nil {
+		return nil, err
 	}
-	switch strings.ToLower(conf.Dialect) {
-	case "mysql":
-		db, err := gorm.Open(mysql.Open(conf.DSN), c)
-		return db, err
-	case "sqlite", "sqlite3":
-		db, err := gorm.Open(sqlite.Open(conf.DSN), c)
-		return db, err
-	default:
-		return nil, fmt.Errorf("不支持的数据库: [%s]", conf.Dialect)
+	if !conf.Scope.Disabled {
+		if err = db.Use(NewScopePlugin(conf.Scope)); err != nil {
+			return nil, err
+		}
+	}
+	if err = setupPool(db, conf); err != nil {
+		return nil, err
+	}
+	if err = pingDb(db, conf); err != nil {
+		closeDb(db)
+		return nil, err
 	}
+	return db, nil
 }
 
 // -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
@@ -51,7 +71,18 @@ func SafeStr(s string, size int) string {
 	return string(cc[:size])
 }
 
-// SetDbLogger 打开调试日志
+// newDbLoggerByConfig 按配置创建日志
+func newDbLoggerByConfig(conf DbConfig) *DbLogger {
+	slow := time.Duration(conf.SlowThreshold) * time.Millisecond
+	if conf.SlowThreshold == 0 {
+		slow = 200 * time.Millisecond
+	} else if conf.SlowThreshold < 0 {
+		slow = 0
+	}
+	return NewDbLogger(nil, ParseDbLogLevel(conf.LogLevel), slow)
+}
+
+// SetDbLogger 打开调试日志，等级参考 ParseDbLogLevel
 func SetDbLogger(db *gorm.DB, logSqlEnabled string) *gorm.DB {
 	if logSqlEnabled == "1" || logSqlEnabled == "true" || logSqlEnabled == "info" {
 		db = db.Debug()
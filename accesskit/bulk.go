@@ -0,0 +1,161 @@
+package accesskit
+
+import (
+	"fmt"
+	"github.com/xtulnx/go-srv/errno"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// 批量写入
+//
+//	mysql: INSERT ... ON DUPLICATE KEY UPDATE
+//	sqlite/postgres: INSERT ... ON CONFLICT (...) DO UPDATE
+//
+//	示例:
+//
+//	   res, err := accesskit.Upsert(&dao1.DO, list, accesskit.UpsertOptions{
+//	       BatchSize: 200,
+//	       Conflict:  []field.Expr{dbUser.Mobile},
+//	       Updates:   []field.Expr{dbUser.Name, dbUser.UpdatedAt},
+//	   })
+
+// DefaultBatchSize 默认每批条数
+const DefaultBatchSize = 500
+
+// UpsertOptions 批量写入选项
+type UpsertOptions struct {
+	BatchSize int // 每批条数，默认 DefaultBatchSize
+
+	// 判断冲突的字段（唯一索引），默认主键。 mysql 按表上的唯一索引判断，忽略此项
+	Conflict []field.Expr
+	// 冲突时更新的字段，为空时更新所有字段
+	Updates []field.Expr
+	// 冲突时忽略
+	DoNothing bool
+
+	// 某一批失败后继续写入后面的批次
+	ContinueOnError bool
+	// 不按 size 截断字符串字段。截断时直接修改 models 中的字段
+	NoTruncate bool
+}
+
+// BatchResult 每一批的结果
+type BatchResult struct {
+	Offset       int   // 在原切片中的起始位置
+	Count        int   // 条数
+	RowsAffected int64 // 影响行数。 mysql 中更新的行计为 2
+	Err          error // 错误，为 errno.FailedUpdate
+}
+
+// BatchInsert 分批插入，不处理冲突，参考 Upsert
+func BatchInsert(do *gen.DO, models interface{}, batchSize int) ([]BatchResult, error) {
+	return bulkWrite(do, models, UpsertOptions{BatchSize: batchSize}, nil)
+}
+
+// Upsert 分批插入，冲突时更新
+//
+//	models 为 model 的切片（或切片指针）
+//	返回每一批的结果，以及第一个错误
+//
+//	与 gorm 的 Create 一样会修改 models ：回填主键；超过 size 的字符串字段被截断（ NoTruncate 时不截断）
+func Upsert(do *gen.DO, models interface{}, opts UpsertOptions) ([]BatchResult, error) {
+	onConflict := clause.OnConflict{DoNothing: opts.DoNothing}
+	for _, c := range ColsNamesByExpr(opts.Conflict...) {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: c})
+	}
+	if !opts.DoNothing {
+		if len(opts.Updates) > 0 {
+			onConflict.DoUpdates = clause.AssignmentColumns(ColsNamesByExpr(opts.Updates...))
+		} else {
+			onConflict.UpdateAll = true
+		}
+	}
+	return bulkWrite(do, models, opts, onConflict)
+}
+
+func bulkWrite(do *gen.DO, models interface{}, opts UpsertOptions, onConflict clause.Expression) ([]BatchResult, error) {
+	rv := reflect.Indirect(reflect.ValueOf(models))
+	if rv.Kind() != reflect.Slice {
+		return nil, errno.FailedUpdate.WithErr(fmt.Errorf("需要切片: %T", models))
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	db := do.UnderlyingDB()
+	if onConflict != nil {
+		db = db.Clauses(onConflict)
+	}
+
+	var results []BatchResult
+	var firstErr error
+	for i := 0; i < rv.Len(); i += size {
+		j := i + size
+		if j > rv.Len() {
+			j = rv.Len()
+		}
+		chunk := rv.Slice(i, j)
+		res := BatchResult{Offset: i, Count: j - i}
+		if !opts.NoTruncate {
+			if err := truncateStrings(db, chunk); err != nil {
+				res.Err = errno.FailedUpdate.WithErr(err)
+			}
+		}
+		if res.Err == nil {
+			tx := db.Session(&gorm.Session{}).Create(chunk.Interface())
+			res.RowsAffected = tx.RowsAffected
+			if tx.Error != nil {
+				res.Err = errno.FailedUpdate.WithErr(tx.Error)
+			}
+		}
+		results = append(results, res)
+		if res.Err != nil {
+			if firstErr == nil {
+				firstErr = res.Err
+			}
+			if !opts.ContinueOnError {
+				break
+			}
+		}
+	}
+	return results, firstErr
+}
+
+// truncateStrings 按字段的 size 截断字符串，参考 SafeStr ；直接修改 rows 中的元素
+func truncateStrings(db *gorm.DB, rows reflect.Value) error {
+	if rows.Len() == 0 {
+		return nil
+	}
+	elem := reflect.Indirect(rows.Index(0))
+	if elem.Kind() != reflect.Struct {
+		return nil
+	}
+	sch, err := schema.Parse(elem.Addr().Interface(), schemaCache, db.NamingStrategy)
+	if err != nil {
+		return err
+	}
+	var fields []*schema.Field
+	for _, f := range sch.Fields {
+		if f.Size > 0 && f.FieldType.Kind() == reflect.String && f.DBName != "" {
+			fields = append(fields, f)
+		}
+	}
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		if !row.IsValid() {
+			continue
+		}
+		for _, f := range fields {
+			v := f.ReflectValueOf(db.Statement.Context, row)
+			if s := v.String(); len(s) > f.Size {
+				v.SetString(SafeStr(s, f.Size))
+			}
+		}
+	}
+	return nil
+}
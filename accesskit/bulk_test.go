@@ -0,0 +1,94 @@
+package accesskit
+
+import (
+	"errors"
+	"github.com/xtulnx/go-srv/errno"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"strconv"
+	"testing"
+)
+
+type bulkItem struct {
+	ID   uint
+	Code string `gorm:"size:8;uniqueIndex"`
+	Name string `gorm:"size:4"`
+}
+
+func TestBulkWrite(t *testing.T) {
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:bulk?mode=memory&cache=shared"}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&bulkItem{}); err != nil {
+		t.Fatal(err)
+	}
+	var do gen.DO
+	do.UseDB(db)
+	do.UseModel(&bulkItem{})
+	count := func() int64 {
+		var n int64
+		db.Model(&bulkItem{}).Count(&n)
+		return n
+	}
+
+	// 分批插入，回填主键，截断超长的字符串
+	var list []*bulkItem
+	for i := 0; i < 5; i++ {
+		list = append(list, &bulkItem{Code: "c" + strconv.Itoa(i), Name: "名称很长的" + strconv.Itoa(i)})
+	}
+	res, err := BatchInsert(&do, list, 2)
+	if err != nil || len(res) != 3 || res[2].Offset != 4 || res[2].Count != 1 || res[0].RowsAffected != 2 {
+		t.Fatalf("BatchInsert: %+v %v", res, err)
+	}
+	if list[4].ID == 0 || list[0].Name != "名称很长" || count() != 5 {
+		t.Fatalf("BatchInsert: %+v", list[0])
+	}
+
+	// 冲突时只更新指定的字段
+	code, name := field.NewString("t_bulk_item", "code"), field.NewString("t_bulk_item", "name")
+	up := []*bulkItem{{Code: "c0", Name: "新"}, {Code: "c9", Name: "九"}}
+	if _, err = Upsert(&do, up, UpsertOptions{Conflict: []field.Expr{code}, Updates: []field.Expr{name}}); err != nil {
+		t.Fatal(err)
+	}
+	var got bulkItem
+	db.Where("code = ?", "c0").First(&got)
+	if got.ID != list[0].ID || got.Name != "新" || count() != 6 {
+		t.Fatalf("Upsert: %+v %d", got, count())
+	}
+	if _, err = Upsert(&do, []*bulkItem{{Code: "c9", Name: "x"}}, UpsertOptions{Conflict: []field.Expr{code}, DoNothing: true}); err != nil {
+		t.Fatal(err)
+	}
+	var c9 bulkItem
+	db.Where("code = ?", "c9").First(&c9)
+	if c9.Name != "九" {
+		t.Fatalf("DoNothing: %+v", c9)
+	}
+
+	// 不截断
+	long := []bulkItem{{Code: "n1", Name: "abcdefg"}}
+	if _, err = BatchInsert(&do, long, 0); err != nil {
+		t.Fatal(err)
+	}
+	if long[0].Name != "abcd" {
+		t.Fatalf("truncate: %+v", long[0])
+	}
+	long = []bulkItem{{Code: "n2", Name: "abcdefg"}}
+	if _, err = Upsert(&do, &long, UpsertOptions{NoTruncate: true, DoNothing: true}); err != nil || long[0].Name != "abcdefg" {
+		t.Fatalf("NoTruncate: %+v %v", long[0], err)
+	}
+
+	// 某一批失败
+	dup := []*bulkItem{{Code: "d1"}, {Code: "c1"}, {Code: "d2"}, {Code: "d3"}}
+	res, err = BatchInsert(&do, dup, 2)
+	if !errors.Is(err, errno.FailedUpdate) || len(res) != 1 {
+		t.Fatalf("stop on error: %+v %v", res, err)
+	}
+	res, err = bulkWrite(&do, []*bulkItem{{Code: "c2"}, {Code: "d4"}}, UpsertOptions{BatchSize: 1, ContinueOnError: true}, nil)
+	if err == nil || len(res) != 2 || res[0].Err == nil || res[1].Err != nil {
+		t.Fatalf("ContinueOnError: %+v %v", res, err)
+	}
+	if _, err = BatchInsert(&do, bulkItem{}, 1); !errors.Is(err, errno.FailedUpdate) {
+		t.Fatalf("not slice: %v", err)
+	}
+}
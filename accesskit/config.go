@@ -4,4 +4,28 @@ package accesskit
 type DbConfig struct {
 	DSN     string `json:"dsn" toml:"dsn"`
 	Dialect string `json:"dialect" toml:"dialect"`
+
+	// 只读从库的 DSN 列表，为空时读写都走主库（DSN）
+	Replicas []string `json:"replicas" toml:"replicas"`
+	// 从库负载均衡策略: random(默认) round_robin
+	Policy string `json:"policy" toml:"policy"`
+
+	// 连接池，为 0 时使用 database/sql 的默认值。主库与从库使用相同的设置
+	MaxOpenConns    int `json:"max_open_conns" toml:"max_open_conns"`         // 最大连接数
+	MaxIdleConns    int `json:"max_idle_conns" toml:"max_idle_conns"`         // 最大空闲连接数
+	ConnMaxLifetime int `json:"conn_max_lifetime" toml:"conn_max_lifetime"`   // 连接最长使用时间（秒）
+	ConnMaxIdleTime int `json:"conn_max_idle_time" toml:"conn_max_idle_time"` // 连接最长空闲时间（秒）
+
+	// 启动时检测连接，最多尝试的次数，为 0 时不检测
+	PingAttempts int `json:"ping_attempts" toml:"ping_attempts"`
+	// 检测失败后的重试间隔（毫秒），每次翻倍，默认 500
+	PingBackoff int `json:"ping_backoff" toml:"ping_backoff"`
+
+	// SQL 日志等级: silent error warn(默认) info ，通过 logkit 输出
+	LogLevel string `json:"log_level" toml:"log_level"`
+	// 慢查询阈值（毫秒），默认 200 ，小于 0 时不记录慢查询
+	SlowThreshold int `json:"slow_threshold" toml:"slow_threshold"`
+
+	// 租户、审计字段与软删除
+	Scope ScopeConfig `json:"scope" toml:"scope"`
 }
@@ -0,0 +1,69 @@
+package accesskit
+
+import (
+	"fmt"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+)
+
+// 数据库类型，内置:
+//
+//	mysql
+//	sqlite, sqlite3
+//	postgres, postgresql, pgsql
+//	sqlserver, mssql
+
+// DialectorFunc 根据 DSN 创建连接
+type DialectorFunc func(dsn string) gorm.Dialector
+
+var (
+	dialectMu sync.RWMutex
+	dialects  = map[string]DialectorFunc{}
+)
+
+func init() {
+	RegisterDialect("mysql", mysql.Open)
+	for _, name := range []string{"sqlite", "sqlite3"} {
+		RegisterDialect(name, sqlite.Open)
+	}
+	for _, name := range []string{"postgres", "postgresql", "pgsql"} {
+		RegisterDialect(name, postgres.Open)
+	}
+	for _, name := range []string{"sqlserver", "mssql"} {
+		RegisterDialect(name, sqlserver.Open)
+	}
+}
+
+// RegisterDialect 注册数据库类型，名称不区分大小写，同名时覆盖
+//
+//	示例: 测试时使用内存数据库
+//
+//	   accesskit.RegisterDialect("mock", func(dsn string) gorm.Dialector {
+//	       return sqlite.Open("file::memory:?cache=shared")
+//	   })
+func RegisterDialect(name string, open DialectorFunc) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	name = strings.ToLower(name)
+	if open == nil {
+		delete(dialects, name)
+		return
+	}
+	dialects[name] = open
+}
+
+// openDialector 根据数据库类型创建连接
+func openDialector(dialect, dsn string) (gorm.Dialector, error) {
+	dialectMu.RLock()
+	open, ok := dialects[strings.ToLower(dialect)]
+	dialectMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的数据库: [%s]", dialect)
+	}
+	return open(dsn), nil
+}
@@ -1,6 +1,7 @@
 package accesskit
 
 import (
+	"fmt"
 	"gorm.io/gen"
 	"gorm.io/gen/field"
 	"gorm.io/gorm"
@@ -11,7 +12,7 @@ import (
 // 补充 gorm/gen 辅助
 
 // 合并多个查询表达式，转成 sql 与 参数对象
-//  columns: field.Expr, string, clause.Expr
+//  columns: field.Expr, string, clause.Expr, JSONExpr ；其它类型添加错误到 stmt
 func buildExpr(stmt *gorm.Statement, columns ...interface{}) (query []string, args []interface{}) {
 	for _, e := range columns {
 		switch v := e.(type) {
@@ -24,13 +25,19 @@ func buildExpr(stmt *gorm.Statement, columns ...interface{}) (query []string, ar
 		case clause.Expr:
 			query = append(query, v.SQL)
 			args = append(args, v.Vars...)
+		case JSONExpr:
+			x := v.BuildExpr(stmt)
+			query = append(query, x.SQL)
+			args = append(args, x.Vars...)
+		default:
+			_ = stmt.AddError(fmt.Errorf("不支持的表达式类型: %T", e))
 		}
 	}
 	return query, args
 }
 
 // Select 字段
-//  columns: field.Expr, string, clause.Expr
+//  columns: field.Expr, string, clause.Expr, JSONExpr
 func Select(do *gen.DO, columns ...interface{}) {
 	db := do.UnderlyingDB()
 	query, args := buildExpr(db.Statement, columns...)
@@ -39,7 +46,7 @@ func Select(do *gen.DO, columns ...interface{}) {
 }
 
 // SelectAppend 增加额外的 Select 字段
-//  columns: 支持类型 field.Expr, string, clause.Expr
+//  columns: 支持类型 field.Expr, string, clause.Expr, JSONExpr
 //
 //  示例:
 //
@@ -80,6 +87,26 @@ func SelectAppend(do *gen.DO, columns ...interface{}) {
 	do.ReplaceDB(db)
 }
 
+// Where 增加查询条件，每个条件加上括号，之间为 AND
+//  conds: 支持类型 field.Expr, string, clause.Expr, JSONExpr
+//
+//  示例:
+//
+//     accesskit.Where(&dao1.DO, accesskit.JSONQuery(dbUser.Extra).HasKey("profile"))
+//
+func Where(do *gen.DO, conds ...interface{}) {
+	db := do.UnderlyingDB()
+	query, args := buildExpr(db.Statement, conds...)
+	if len(query) == 0 {
+		return
+	}
+	for i, q := range query {
+		query[i] = "(" + q + ")"
+	}
+	db = db.Where(strings.Join(query, " AND "), args...)
+	do.ReplaceDB(db)
+}
+
 func ColsNamesByExpr(expr ...field.Expr) []string {
 	names := make([]string, len(expr))
 	for i := range expr {
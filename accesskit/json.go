@@ -1,4 +1,215 @@
 package accesskit
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
 // 对 JSON 操作，只支持 maridb 和 mysql8+
 // datatypes.JSONQueryExpression
+//
+// sqlite 使用 json_extract / json_each 模拟，便于本地测试；
+// 对象、数组的 Contains 在 sqlite 下只能整体比较；
+// 其它数据库返回 ErrJSONDialect 错误
+//
+//	示例:
+//
+//	   // 查询条件
+//	   accesskit.Where(&dao1.DO, accesskit.JSONQuery(dbUser.Extra).Equals("profile.city", "深圳"))
+//	   db.Where(accesskit.JSONQuery("extra").HasKey("profile", "tags"))
+//
+//	   // 作为字段
+//	   accesskit.SelectAppend(&dao1.DO, accesskit.JSONQuery(dbUser.Extra).Extract("profile.city").As("city"))
+
+// ErrJSONDialect JSON 查询不支持的数据库，生成 SQL 时加到 db.Error
+var ErrJSONDialect = errors.New("JSON 查询不支持该数据库")
+
+// JSONColumn JSON 字段
+type JSONColumn struct {
+	column interface{} // field.Expr, string
+}
+
+// JSONQuery 对 JSON 字段查询
+//
+//	column: field.Expr 或 字段名
+func JSONQuery(column interface{}) JSONColumn {
+	return JSONColumn{column: column}
+}
+
+// JSONExpr JSON 查询表达式，根据数据库类型生成 SQL
+//
+//	可用于 gorm 的 Where ，以及 Where / Select / SelectAppend
+type JSONExpr struct {
+	column interface{}
+	alias  string
+	build  func(dialect, col string) clause.Expr
+}
+
+// jsonPath 转成 $.a.b 的形式
+func jsonPath(path string) string {
+	if path == "" || path == "$" {
+		return "$"
+	}
+	if strings.HasPrefix(path, "$") {
+		return path
+	}
+	if path[0] == '[' {
+		return "$" + path
+	}
+	return "$." + path
+}
+
+// jsonValue 转成 JSON 文本
+func jsonValue(v interface{}) string {
+	if b, ok := v.(json.RawMessage); ok {
+		return string(b)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func (c JSONColumn) expr(build func(dialect, col string) clause.Expr) JSONExpr {
+	return JSONExpr{column: c.column, build: build}
+}
+
+// Extract 取出指定路径的值（字符串不带引号）
+func (c JSONColumn) Extract(path string) JSONExpr {
+	return c.expr(func(dialect, col string) clause.Expr {
+		if dialect == "sqlite" {
+			return clause.Expr{SQL: "json_extract(" + col + ", ?)", Vars: []interface{}{jsonPath(path)}}
+		}
+		return clause.Expr{SQL: "JSON_UNQUOTE(JSON_EXTRACT(" + col + ", ?))", Vars: []interface{}{jsonPath(path)}}
+	})
+}
+
+// Equals 指定路径的值等于 value
+func (c JSONColumn) Equals(path string, value interface{}) JSONExpr {
+	e := c.Extract(path)
+	build := e.build
+	e.build = func(dialect, col string) clause.Expr {
+		x := build(dialect, col)
+		return clause.Expr{SQL: x.SQL + " = ?", Vars: append(x.Vars, value)}
+	}
+	return e
+}
+
+// HasKey 包含所有指定的路径
+func (c JSONColumn) HasKey(keys ...string) JSONExpr {
+	return c.hasKey(true, keys)
+}
+
+// HasAnyKey 包含任意一个指定的路径
+func (c JSONColumn) HasAnyKey(keys ...string) JSONExpr {
+	return c.hasKey(false, keys)
+}
+
+func (c JSONColumn) hasKey(all bool, keys []string) JSONExpr {
+	return c.expr(func(dialect, col string) clause.Expr {
+		vars := make([]interface{}, len(keys))
+		for i, k := range keys {
+			vars[i] = jsonPath(k)
+		}
+		if dialect == "sqlite" {
+			sep := " OR "
+			if all {
+				sep = " AND "
+			}
+			cc := make([]string, len(keys))
+			for i := range keys {
+				cc[i] = "json_type(" + col + ", ?) IS NOT NULL"
+			}
+			return clause.Expr{SQL: "(" + strings.Join(cc, sep) + ")", Vars: vars}
+		}
+		mode := "one"
+		if all {
+			mode = "all"
+		}
+		return clause.Expr{
+			SQL:  "JSON_CONTAINS_PATH(" + col + ", '" + mode + "'" + strings.Repeat(", ?", len(keys)) + ")",
+			Vars: vars,
+		}
+	})
+}
+
+// Contains 指定路径的值包含 value 。 value 会转成 JSON ，可以是标量、对象或数组
+func (c JSONColumn) Contains(path string, value interface{}) JSONExpr {
+	return c.expr(func(dialect, col string) clause.Expr {
+		p, v := jsonPath(path), jsonValue(value)
+		if dialect == "sqlite" {
+			return clause.Expr{
+				SQL: "(json_extract(" + col + ", ?) = json_extract(?, '$')" +
+					" OR EXISTS (SELECT 1 FROM json_each(" + col + ", ?) AS je WHERE je.value = json_extract(?, '$')))",
+				Vars: []interface{}{p, v, p, v},
+			}
+		}
+		return clause.Expr{SQL: "JSON_CONTAINS(" + col + ", ?, ?)", Vars: []interface{}{v, p}}
+	})
+}
+
+// MemberOf 指定路径的数组中包含 values 的任意一个
+func (c JSONColumn) MemberOf(path string, values ...interface{}) JSONExpr {
+	return c.expr(func(dialect, col string) clause.Expr {
+		p := jsonPath(path)
+		if len(values) == 0 {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+		if dialect == "sqlite" {
+			return clause.Expr{
+				SQL:  "EXISTS (SELECT 1 FROM json_each(" + col + ", ?) AS je WHERE je.value IN ?)",
+				Vars: []interface{}{p, values},
+			}
+		}
+		cc := make([]string, len(values))
+		vars := make([]interface{}, 0, len(values)*2)
+		for i, v := range values {
+			cc[i] = "JSON_CONTAINS(" + col + ", ?, ?)"
+			vars = append(vars, jsonValue(v), p)
+		}
+		return clause.Expr{SQL: "(" + strings.Join(cc, " OR ") + ")", Vars: vars}
+	})
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// As 作为查询字段时的别名
+func (e JSONExpr) As(alias string) JSONExpr {
+	e.alias = alias
+	return e
+}
+
+// BuildExpr 根据 stmt 的数据库类型生成表达式；不是 mysql 、 sqlite 时添加 ErrJSONDialect 错误，
+// 字段不是 field.Expr 或字符串时也添加错误
+func (e JSONExpr) BuildExpr(stmt *gorm.Statement) clause.Expr {
+	dialect := stmt.Dialector.Name()
+	if dialect != "mysql" && dialect != "sqlite" {
+		_ = stmt.AddError(fmt.Errorf("%w: [%s]", ErrJSONDialect, dialect))
+		return clause.Expr{SQL: "1 = 0"}
+	}
+	var col string
+	switch v := e.column.(type) {
+	case field.Expr:
+		col = v.BuildColumn(stmt, field.WithTable).String()
+	case string:
+		col = stmt.Quote(v)
+	default:
+		_ = stmt.AddError(fmt.Errorf("JSON 查询不支持的字段类型: %T", e.column))
+		return clause.Expr{SQL: "1 = 0"}
+	}
+	x := e.build(dialect, col)
+	if e.alias != "" {
+		x.SQL += " AS " + stmt.Quote(e.alias)
+	}
+	return x
+}
+
+// Build 实现 clause.Expression
+func (e JSONExpr) Build(builder clause.Builder) {
+	if stmt, ok := builder.(*gorm.Statement); ok {
+		e.BuildExpr(stmt).Build(builder)
+	}
+}
@@ -0,0 +1,106 @@
+package accesskit
+
+import (
+	"context"
+	"errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"testing"
+)
+
+type jsonUser struct {
+	ID    uint
+	Extra string
+}
+
+// otherDialector 名称不是 mysql 、 sqlite 的数据库
+type otherDialector struct {
+	gorm.Dialector
+}
+
+func (otherDialector) Name() string { return "postgres" }
+
+func TestJSONQuery(t *testing.T) {
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:json?mode=memory&cache=shared"}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&jsonUser{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create([]*jsonUser{
+		{Extra: `{"profile":{"city":"深圳"},"tags":["a","b"],"n":1}`},
+		{Extra: `{"profile":{"city":"广州"},"tags":["c"]}`},
+		{Extra: `{"tags":[]}`},
+	})
+
+	extra := JSONQuery("extra")
+	for name, c := range map[string]struct {
+		expr JSONExpr
+		want []uint
+	}{
+		"Equals":         {extra.Equals("profile.city", "深圳"), []uint{1}},
+		"Equals field":   {JSONQuery(field.NewString("t_json_user", "extra")).Equals("$.profile.city", "广州"), []uint{2}},
+		"HasKey":         {extra.HasKey("profile", "n"), []uint{1}},
+		"HasAnyKey":      {extra.HasAnyKey("n", "profile"), []uint{1, 2}},
+		"Contains":       {extra.Contains("tags", "c"), []uint{2}},
+		"Contains value": {extra.Contains("profile.city", "广州"), []uint{2}},
+		"MemberOf":       {extra.MemberOf("tags", "a", "c"), []uint{1, 2}},
+		"MemberOf none":  {extra.MemberOf("tags"), []uint{}},
+	} {
+		ids := []uint{}
+		if err := db.Model(&jsonUser{}).Where(c.expr).Order("id").Pluck("id", &ids).Error; err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(ids, c.want) {
+			t.Errorf("%s = %v, want %v", name, ids, c.want)
+		}
+	}
+
+	// 作为字段
+	var rows []struct {
+		ID   uint
+		City *string
+	}
+	if err = db.Model(&jsonUser{}).Select("id, ?", extra.Extract("profile.city").As("city")).Order("id").Scan(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0].City == nil || *rows[0].City != "深圳" || rows[2].City != nil {
+		t.Fatalf("Extract: %+v", rows)
+	}
+
+	// Where 的每个条件加上括号
+	var do gen.DO
+	do.UseDB(db)
+	do.UseModel(&jsonUser{})
+	q := do.WithContext(context.Background()).(*gen.DO)
+	Where(q, extra.HasKey("n"), clause.Expr{SQL: "id = ? OR id = ?", Vars: []interface{}{2, 3}})
+	var found []jsonUser
+	if err = q.Scan(&found); err != nil || len(found) != 0 {
+		t.Fatalf("Where: %+v %v", found, err)
+	}
+
+	// 不支持的字段、表达式类型
+	var list []jsonUser
+	if err = db.Where(JSONQuery(1).HasKey("profile")).Find(&list).Error; err == nil {
+		t.Fatal("expect column type error")
+	}
+	q = do.WithContext(context.Background()).(*gen.DO)
+	Where(q, 1)
+	if err = q.Scan(&found); err == nil {
+		t.Fatal("expect expr type error")
+	}
+
+	// 不支持的数据库
+	other, err := gorm.Open(otherDialector{sqlite.Open("file:json?mode=memory&cache=shared")}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = other.Where(extra.HasKey("profile")).Find(&list).Error; !errors.Is(err, ErrJSONDialect) {
+		t.Fatalf("unsupported dialect: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package accesskit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/xtulnx/go-srv/logkit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gorm 日志，通过 logkit 输出，与其他日志使用相同的文件
+//
+//	字段: sql rows elapsed(毫秒) caller trace_id
+
+// accesskitPkg 本包的路径，查找调用位置时跳过
+var accesskitPkg = reflect.TypeOf(DbLogger{}).PkgPath()
+
+// fileWithLineNum 调用位置，按包路径跳过 gorm.io 下的包（包括 gen 、驱动）、本包，以及 gen 生成的 *.gen.go
+func fileWithLineNum() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if f.File != "" && !skipFrame(f) {
+			return f.File + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return utils.FileWithLineNum()
+}
+
+func skipFrame(f runtime.Frame) bool {
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	if strings.HasSuffix(f.File, ".gen.go") {
+		return true
+	}
+	pkg := funcPkg(f.Function)
+	return strings.HasPrefix(pkg, "gorm.io/") || pkg == accesskitPkg || pkg == "runtime" || pkg == "reflect"
+}
+
+// funcPkg 函数所在的包，如 gorm.io/gorm.(*DB).Find => gorm.io/gorm
+func funcPkg(name string) string {
+	i := strings.LastIndexByte(name, '/') + 1
+	if j := strings.IndexByte(name[i:], '.'); j >= 0 {
+		return name[:i+j]
+	}
+	return name
+}
+
+// DbLogger 实现 logger.Interface
+type DbLogger struct {
+	entry *logrus.Entry
+
+	level                logger.LogLevel
+	slowThreshold        time.Duration
+	ignoreRecordNotFound bool
+}
+
+// ParseDbLogLevel 日志等级: silent error warn info debug ，以及兼容 SetDbLogger 的 "1" "true"
+func ParseDbLogLevel(s string) logger.LogLevel {
+	switch strings.ToLower(s) {
+	case "silent", "off":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn", "":
+		return logger.Warn
+	case "1", "true", "info", "debug":
+		return logger.Info
+	}
+	return logger.Warn
+}
+
+// NewDbLogger 创建 gorm 日志
+//
+//	entry 为空时使用 logkit.ForTask("db")
+//	slowThreshold 慢查询阈值，为 0 时不记录慢查询
+func NewDbLogger(entry *logrus.Entry, level logger.LogLevel, slowThreshold time.Duration) *DbLogger {
+	if entry == nil {
+		entry = logkit.ForTask("db")
+	}
+	return &DbLogger{
+		entry:                entry,
+		level:                level,
+		slowThreshold:        slowThreshold,
+		ignoreRecordNotFound: true,
+	}
+}
+
+// LogMode 修改日志等级
+func (l *DbLogger) LogMode(level logger.LogLevel) logger.Interface {
+	n := *l
+	n.level = level
+	return &n
+}
+
+// IgnoreRecordNotFound 是否忽略 gorm.ErrRecordNotFound ，默认忽略
+func (l *DbLogger) IgnoreRecordNotFound(ignore bool) *DbLogger {
+	n := *l
+	n.ignoreRecordNotFound = ignore
+	return &n
+}
+
+func (l *DbLogger) withContext(ctx context.Context) *logrus.Entry {
+	e := l.entry.WithContext(ctx)
+	if id := logkit.TraceID(ctx); id != "" {
+		e = e.WithField("trace_id", id)
+	}
+	return e
+}
+
+func (l *DbLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.withContext(ctx).WithField("caller", fileWithLineNum()).Infof(msg, data...)
+	}
+}
+
+func (l *DbLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.withContext(ctx).WithField("caller", fileWithLineNum()).Warnf(msg, data...)
+	}
+}
+
+func (l *DbLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.withContext(ctx).WithField("caller", fileWithLineNum()).Errorf(msg, data...)
+	}
+}
+
+// Trace 记录 SQL ：出错为 error ，慢查询为 warn ，其他为 info
+func (l *DbLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	fields := func() *logrus.Entry {
+		sql, rows := fc()
+		return l.withContext(ctx).WithFields(logrus.Fields{
+			"sql":     sql,
+			"rows":    rows,
+			"elapsed": float64(elapsed.Nanoseconds()) / 1e6,
+			"caller":  fileWithLineNum(),
+		})
+	}
+	switch {
+	case err != nil && l.level >= logger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.ignoreRecordNotFound):
+		fields().WithError(err).Error("sql error")
+	case l.slowThreshold != 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		fields().Warn(fmt.Sprintf("slow sql >= %v", l.slowThreshold))
+	case l.level >= logger.Info:
+		fields().Info("sql")
+	}
+}
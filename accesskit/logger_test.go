@@ -0,0 +1,54 @@
+package accesskit
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+)
+
+func TestDbLoggerCaller(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:logger?mode=memory&cache=shared"}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = db.Session(&gorm.Session{Logger: NewDbLogger(logrus.NewEntry(log), logger.Info, 0)})
+	if err = db.AutoMigrate(&txItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 直接使用 gorm ，以及通过 gen.DO
+	var list []txItem
+	db.Find(&list)
+	var do gen.DO
+	do.UseDB(db)
+	do.UseModel(&txItem{})
+	_, _ = do.Count()
+	entries := hook.AllEntries()
+	if len(entries) < 2 {
+		t.Fatalf("entries = %d, want >= 2", len(entries))
+	}
+	for _, e := range entries[len(entries)-2:] {
+		if caller, _ := e.Data["caller"].(string); !strings.Contains(caller, "logger_test.go:") {
+			t.Fatalf("caller = %q (%v)", caller, e.Data["sql"])
+		}
+	}
+}
+
+func TestFuncPkg(t *testing.T) {
+	for name, want := range map[string]string{
+		"gorm.io/gorm.(*DB).Find":                              "gorm.io/gorm",
+		"gorm.io/gorm/callbacks.Query":                         "gorm.io/gorm/callbacks",
+		"github.com/xtulnx/go-srv/accesskit.(*DbLogger).Trace": "github.com/xtulnx/go-srv/accesskit",
+		"main.main": "main",
+	} {
+		if got := funcPkg(name); got != want {
+			t.Errorf("funcPkg(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package accesskit
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 数据库版本迁移
+//
+//	已执行的版本记录在表 {tablePrefix}schema_migration
+//	执行期间通过表 {tablePrefix}schema_migration_lock 加锁，避免多个实例同时迁移
+//
+//	示例:
+//
+//	   m := accesskit.NewMigrator(db,
+//	       accesskit.Migration{
+//	           Version: 2022100101,
+//	           Name:    "create user",
+//	           UpSQL:   []string{"create table t_user (id integer primary key, name varchar(64))"},
+//	           DownSQL: []string{"drop table t_user"},
+//	       },
+//	       accesskit.Migration{
+//	           Version: 2022100201,
+//	           Name:    "init admin",
+//	           Up: func(tx *gorm.DB) error {
+//	               return tx.Exec("insert into t_user (id, name) values (1, 'admin')").Error
+//	           },
+//	       },
+//	   )
+//	   err := m.Up(ctx)
+
+// Migration 一个版本的迁移。 SQL 与 函数 同时存在时，先执行 SQL
+type Migration struct {
+	Version int64  // 版本号，按从小到大执行，不能重复
+	Name    string // 描述
+
+	UpSQL   []string // 升级语句，每项一条
+	DownSQL []string // 回滚语句，每项一条
+
+	Up   func(tx *gorm.DB) error // 升级
+	Down func(tx *gorm.DB) error // 回滚
+}
+
+// SchemaMigration 已执行的版本
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"size:255" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// SchemaMigrationLock 迁移锁，只有一行
+type SchemaMigrationLock struct {
+	ID       int       `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string    `gorm:"size:128"`
+	LockedAt time.Time `gorm:"index"`
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+
+	// LockTimeout 锁超过这个时间视为失效（持有者异常退出），默认 10 分钟
+	LockTimeout time.Duration
+	// LockWait 等待其他实例释放锁的最长时间，默认 1 分钟
+	LockWait time.Duration
+}
+
+// NewMigrator 创建迁移执行器
+func NewMigrator(db *gorm.DB, migrations ...Migration) *Migrator {
+	m := &Migrator{
+		db:          db,
+		LockTimeout: 10 * time.Minute,
+		LockWait:    time.Minute,
+	}
+	m.Add(migrations...)
+	return m
+}
+
+// Add 增加迁移
+func (m *Migrator) Add(migrations ...Migration) *Migrator {
+	m.migrations = append(m.migrations, migrations...)
+	sort.SliceStable(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return m
+}
+
+// check 检查版本号
+func (m *Migrator) check() error {
+	for i := range m.migrations {
+		if m.migrations[i].Version <= 0 {
+			return fmt.Errorf("无效的迁移版本: [%d] %s", m.migrations[i].Version, m.migrations[i].Name)
+		}
+		if i > 0 && m.migrations[i].Version == m.migrations[i-1].Version {
+			return fmt.Errorf("重复的迁移版本: [%d]", m.migrations[i].Version)
+		}
+	}
+	return nil
+}
+
+// session 统一走主库
+func (m *Migrator) session(ctx context.Context) *gorm.DB {
+	return UsePrimary(m.db.WithContext(ctx))
+}
+
+// init 创建版本表与锁表
+func (m *Migrator) init(ctx context.Context) error {
+	return m.db.WithContext(ctx).AutoMigrate(&SchemaMigration{}, &SchemaMigrationLock{})
+}
+
+// lock 加锁，返回解锁函数
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	host, _ := os.Hostname()
+	owner := host + ":" + strconv.Itoa(os.Getpid()) + ":" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	deadline := time.Now().Add(m.LockWait)
+	for {
+		now := time.Now()
+		err := m.session(ctx).Create(&SchemaMigrationLock{ID: 1, Owner: owner, LockedAt: now}).Error
+		if err == nil {
+			return func() {
+				m.session(context.Background()).Where("owner = ?", owner).Delete(&SchemaMigrationLock{ID: 1})
+			}, nil
+		}
+		// 清理失效的锁
+		m.session(ctx).Where("locked_at < ?", now.Add(-m.LockTimeout)).Delete(&SchemaMigrationLock{ID: 1})
+		if now.After(deadline) {
+			return nil, fmt.Errorf("等待迁移锁超时: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Applied 已执行的版本，按版本号排序
+func (m *Migrator) Applied(ctx context.Context) ([]SchemaMigration, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	var list []SchemaMigration
+	err := m.session(ctx).Order("version").Find(&list).Error
+	return list, err
+}
+
+// Pending 未执行的迁移
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+	var list []Migration
+	for _, a := range m.migrations {
+		if !done[a.Version] {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+// Up 执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo 执行未执行的迁移，直到指定版本（包含）。 version 为 0 表示全部
+func (m *Migrator) UpTo(ctx context.Context, version int64) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, a := range pending {
+		if version > 0 && a.Version > version {
+			break
+		}
+		if err = m.apply(ctx, a, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 回滚最近执行的 steps 个版本
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, a := range m.migrations {
+		known[a.Version] = a
+	}
+	for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+		a, ok := known[applied[i].Version]
+		if !ok {
+			return fmt.Errorf("找不到迁移版本: [%d] %s", applied[i].Version, applied[i].Name)
+		}
+		if err = m.apply(ctx, a, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply 在事务内执行一个迁移，并更新版本记录
+//
+//	注意: mysql 的 DDL 会隐式提交，失败时无法完全回滚
+func (m *Migrator) apply(ctx context.Context, a Migration, up bool) error {
+	stmts, fc := a.UpSQL, a.Up
+	if !up {
+		stmts, fc = a.DownSQL, a.Down
+	}
+	err := m.session(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, s := range stmts {
+			if err := tx.Exec(s).Error; err != nil {
+				return err
+			}
+		}
+		if fc != nil {
+			if err := fc(tx); err != nil {
+				return err
+			}
+		}
+		if up {
+			return tx.Create(&SchemaMigration{Version: a.Version, Name: a.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Delete(&SchemaMigration{Version: a.Version}).Error
+	})
+	if err != nil {
+		if up {
+			return fmt.Errorf("迁移失败: [%d] %s: %w", a.Version, a.Name, err)
+		}
+		return fmt.Errorf("回滚失败: [%d] %s: %w", a.Version, a.Name, err)
+	}
+	return nil
+}
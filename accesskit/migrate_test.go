@@ -0,0 +1,75 @@
+package accesskit
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestMigrator(t *testing.T) {
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:migrate?mode=memory&cache=shared"}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	m := NewMigrator(db,
+		Migration{
+			Version: 2,
+			Name:    "init admin",
+			Up: func(tx *gorm.DB) error {
+				return tx.Exec("insert into t_user (id, name) values (1, 'admin')").Error
+			},
+			DownSQL: []string{"delete from t_user where id = 1"},
+		},
+		Migration{
+			Version: 1,
+			Name:    "create user",
+			UpSQL:   []string{"create table t_user (id integer primary key, name varchar(64))"},
+			DownSQL: []string{"drop table t_user"},
+		},
+	)
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].Version != 1 || applied[1].Version != 2 {
+		t.Fatalf("applied: %+v", applied)
+	}
+	if !db.Migrator().HasTable("t_schema_migration") {
+		t.Fatal("缺少版本表")
+	}
+
+	// 重复执行不会有变化
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	var cnt int64
+	db.Table("t_user").Count(&cnt)
+	if cnt != 0 {
+		t.Fatalf("回滚后记录数: %d", cnt)
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != 2 {
+		t.Fatalf("pending: %+v", pending)
+	}
+
+	// 锁被占用时等待超时
+	if err = db.Create(&SchemaMigrationLock{ID: 1, Owner: "other", LockedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	m.LockWait = 0
+	if err = m.Up(ctx); err == nil {
+		t.Fatal("应该等待锁超时")
+	}
+}
@@ -0,0 +1,292 @@
+package accesskit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/xtulnx/go-srv/errno"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 分页与排序
+
+const (
+	DefaultPageSize = 20   // 默认每页条数
+	MaxPageSize     = 1000 // 每页最多条数
+)
+
+// PageReq 分页请求，可直接用于接口参数
+type PageReq struct {
+	Page int `json:"page" form:"page"` // 页码，从 1 开始
+	Size int `json:"size" form:"size"` // 每页条数
+
+	// 排序字段，多个用「,」分隔，前缀「-」表示倒序，如 "-created_at,id"
+	Sort string `json:"sort" form:"sort"`
+
+	// 游标，使用上次返回的 PageResult.Cursor 。 非空时按游标（keyset）方式翻页，忽略 Page ；
+	// 游标中记录了排序规则， Sort 需要与上次一致
+	Cursor string `json:"cursor" form:"cursor"`
+	// 使用游标方式，用于请求第一页
+	UseCursor bool `json:"use_cursor" form:"use_cursor"`
+	// 不统计总数，大表翻页时可以避免 count
+	NoCount bool `json:"no_count" form:"no_count"`
+}
+
+// PageResult 分页结果
+type PageResult struct {
+	Total  int64  `json:"total"`            // 总数，NoCount 时为 -1
+	Page   int    `json:"page,omitempty"`   // 当前页码，游标方式时为 0
+	Size   int    `json:"size"`             // 每页条数
+	Cursor string `json:"cursor,omitempty"` // 下一页的游标，为空时表示没有下一页
+}
+
+// normalize 规范页码与条数
+func (p *PageReq) normalize() {
+	if p.Size <= 0 {
+		p.Size = DefaultPageSize
+	} else if p.Size > MaxPageSize {
+		p.Size = MaxPageSize
+	}
+	if p.Page <= 0 {
+		p.Page = 1
+	}
+}
+
+// Offset 偏移量
+func (p PageReq) Offset() int {
+	p.normalize()
+	return (p.Page - 1) * p.Size
+}
+
+type sortItem struct {
+	expr field.Expr
+	name string
+	desc bool
+}
+
+// parseSort 解析排序字段，只允许 sortable 中的字段（按 ColumnName 匹配）
+func parseSort(s string, sortable []field.Expr) ([]sortItem, error) {
+	var items []sortItem
+	names := ColsNamesByExpr(sortable...)
+	for _, a := range strings.Split(s, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		desc := false
+		if a[0] == '-' {
+			desc, a = true, a[1:]
+		} else if a[0] == '+' {
+			a = a[1:]
+		}
+		found := false
+		for i, n := range names {
+			if n == a {
+				items = append(items, sortItem{expr: sortable[i], name: n, desc: desc})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errno.BadRequest.SetMsg("不支持的排序字段: " + a)
+		}
+	}
+	return items, nil
+}
+
+// Paginate 分页查询，结果写入 dest ，同时返回总数
+//
+//	dest 切片指针，元素可以是 model 或 map[string]interface{}
+//	sortable 允许排序的字段；游标方式时，最后一个排序字段需要唯一（如主键），未指定排序时使用 sortable 的第一个
+//
+//	示例:
+//
+//	   var list []*model.User
+//	   page, err := accesskit.Paginate(&dao1.DO, &list, req, dbUser.ID, dbUser.CreatedAt)
+func Paginate(do *gen.DO, dest interface{}, req PageReq, sortable ...field.Expr) (PageResult, error) {
+	req.normalize()
+	res := PageResult{Total: -1, Size: req.Size}
+
+	items, err := parseSort(req.Sort, sortable)
+	if err != nil {
+		return res, err
+	}
+	keyset := req.UseCursor || req.Cursor != ""
+	if keyset && len(items) == 0 {
+		if len(sortable) == 0 {
+			return res, errno.BadRequest.SetMsg("游标分页需要指定排序字段")
+		}
+		items = []sortItem{{expr: sortable[0], name: string(sortable[0].ColumnName())}}
+	}
+
+	if !req.NoCount {
+		if res.Total, err = do.Count(); err != nil {
+			return res, errno.QueryFailed.WithErr(err)
+		}
+	}
+
+	q := *do
+	db := q.UnderlyingDB()
+	for _, a := range items {
+		db = db.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: a.expr.BuildColumn(db.Statement, field.WithTable).String(), Raw: true},
+			Desc:   a.desc,
+		})
+	}
+	if keyset {
+		if req.Cursor != "" {
+			values, e1 := decodeCursor(req.Cursor, items)
+			if e1 != nil {
+				return res, e1
+			}
+			db = db.Where(keysetExpr(db.Statement, items, values))
+		}
+		// 多取一条用于判断是否有下一页
+		db = db.Limit(req.Size + 1)
+	} else {
+		res.Page = req.Page
+		db = db.Offset(req.Offset()).Limit(req.Size)
+	}
+	q.ReplaceDB(db)
+	if err = q.Scan(dest); err != nil {
+		return res, errno.QueryFailed.WithErr(err)
+	}
+
+	if keyset {
+		rv := reflect.Indirect(reflect.ValueOf(dest))
+		if rv.Kind() == reflect.Slice && rv.Len() > req.Size {
+			rv.Set(rv.Slice(0, req.Size))
+			if res.Cursor, err = encodeCursor(db, rv.Index(req.Size-1), items); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// keysetExpr 游标条件，展开为 (a > ?) OR (a = ? AND b > ?) 的形式，以支持混合升降序
+func keysetExpr(stmt *gorm.Statement, items []sortItem, values []interface{}) clause.Expr {
+	var ors []string
+	var vars []interface{}
+	for i := range items {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, items[j].expr.BuildColumn(stmt, field.WithTable).String()+" = ?")
+			vars = append(vars, values[j])
+		}
+		op := " > ?"
+		if items[i].desc {
+			op = " < ?"
+		}
+		ands = append(ands, items[i].expr.BuildColumn(stmt, field.WithTable).String()+op)
+		vars = append(vars, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return clause.Expr{SQL: "(" + strings.Join(ors, " OR ") + ")", Vars: vars}
+}
+
+// schemaCache 解析 model 的缓存
+var schemaCache = &sync.Map{}
+
+// cursorData 游标的内容：排序规则与最后一行的排序字段值
+type cursorData struct {
+	Sort   string        `json:"s"` // 如 -created_at,id ，与请求的排序不一致时报错
+	Values []cursorValue `json:"v"`
+}
+
+// sortSpec 排序规则，字段名与方向，用于校验游标
+func sortSpec(items []sortItem) string {
+	list := make([]string, len(items))
+	for i, a := range items {
+		list[i] = a.name
+		if a.desc {
+			list[i] = "-" + a.name
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+// cursorValue 游标中的值，时间单独保存，避免按字符串比较
+type cursorValue struct {
+	T *time.Time  `json:"t,omitempty"`
+	V interface{} `json:"v,omitempty"`
+}
+
+// encodeCursor 取最后一行的排序字段值作为游标
+func encodeCursor(db *gorm.DB, row reflect.Value, items []sortItem) (string, error) {
+	row = reflect.Indirect(row)
+	values := make([]cursorValue, len(items))
+	switch row.Kind() {
+	case reflect.Map:
+		for i, a := range items {
+			if v := row.MapIndex(reflect.ValueOf(a.name)); v.IsValid() {
+				values[i] = newCursorValue(v.Interface())
+			}
+		}
+	case reflect.Struct:
+		sch, err := schema.Parse(row.Addr().Interface(), schemaCache, db.NamingStrategy)
+		if err != nil {
+			return "", err
+		}
+		for i, a := range items {
+			f := sch.LookUpField(a.name)
+			if f == nil {
+				return "", errno.ConvertDataFailed.SetMsg("游标字段不在结果中: " + a.name)
+			}
+			v, _ := f.ValueOf(context.Background(), row)
+			values[i] = newCursorValue(v)
+		}
+	default:
+		return "", errno.ConvertDataFailed.SetMsg("不支持的结果类型: " + row.Type().String())
+	}
+	b, err := json.Marshal(cursorData{Sort: sortSpec(items), Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor 解析游标，排序规则需要与 items 一致
+func decodeCursor(cursor string, items []sortItem) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errno.BadRequest.WithMsg("无效的游标", err)
+	}
+	var data cursorData
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err = dec.Decode(&data); err != nil {
+		return nil, errno.BadRequest.WithMsg("无效的游标", err)
+	}
+	if data.Sort != sortSpec(items) || len(data.Values) != len(items) {
+		return nil, errno.BadRequest.SetMsg("游标与排序字段不一致")
+	}
+	vars := make([]interface{}, len(items))
+	for i, v := range data.Values {
+		if v.T != nil {
+			vars[i] = *v.T
+		} else {
+			vars[i] = v.V
+		}
+	}
+	return vars, nil
+}
+
+func newCursorValue(v interface{}) cursorValue {
+	switch t := v.(type) {
+	case time.Time:
+		return cursorValue{T: &t}
+	case *time.Time:
+		return cursorValue{T: t}
+	}
+	return cursorValue{V: v}
+}
@@ -0,0 +1,105 @@
+package accesskit
+
+import (
+	"errors"
+	"github.com/xtulnx/go-srv/errno"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type pageItem struct {
+	ID        uint
+	Score     int
+	CreatedAt time.Time
+}
+
+func TestPaginate(t *testing.T) {
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:page?mode=memory&cache=shared"}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&pageItem{}); err != nil {
+		t.Fatal(err)
+	}
+	// score 与 created_at 都有重复
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	scores := []int{3, 1, 3, 2, 3, 1, 2}
+	for i, s := range scores {
+		db.Create(&pageItem{Score: s, CreatedAt: base.Add(time.Duration(i%2) * time.Hour)})
+	}
+	newDO := func() *gen.DO {
+		var do gen.DO
+		do.UseDB(db)
+		do.UseModel(&pageItem{})
+		return &do
+	}
+	id, score, createdAt := field.NewUint("t_page_item", "id"), field.NewInt("t_page_item", "score"), field.NewTime("t_page_item", "created_at")
+
+	for _, sort := range []string{"-score,id", "score,-id", "-created_at,score,id"} {
+		var want []uint
+		db.Model(&pageItem{}).Order(map[string]string{
+			"-score,id":            "score desc, id",
+			"score,-id":            "score, id desc",
+			"-created_at,score,id": "created_at desc, score, id",
+		}[sort]).Pluck("id", &want)
+
+		// 逐页取完，与一次查询的顺序一致
+		var got []uint
+		req := PageReq{Size: 2, Sort: sort, UseCursor: true}
+		for n := 0; ; n++ {
+			var list []*pageItem
+			res, err := Paginate(newDO(), &list, req, id, score, createdAt)
+			if err != nil {
+				t.Fatalf("%s: %v", sort, err)
+			}
+			if n == 0 && res.Total != int64(len(scores)) {
+				t.Fatalf("%s: total %d", sort, res.Total)
+			}
+			for _, a := range list {
+				got = append(got, a.ID)
+			}
+			if res.Cursor == "" || n > len(scores) {
+				break
+			}
+			req.Cursor, req.NoCount = res.Cursor, true
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s = %v, want %v", sort, got, want)
+		}
+	}
+
+	// 结果为 map
+	var rows []map[string]interface{}
+	res, err := Paginate(newDO(), &rows, PageReq{Size: 3, Sort: "-score,id", UseCursor: true, NoCount: true}, id, score)
+	if err != nil || len(rows) != 3 || res.Cursor == "" {
+		t.Fatalf("map: %v %v", rows, err)
+	}
+	rows = nil
+	if _, err = Paginate(newDO(), &rows, PageReq{Size: 3, Sort: "-score,id", Cursor: res.Cursor, NoCount: true}, id, score); err != nil || len(rows) != 3 {
+		t.Fatalf("map: %v %v", rows, err)
+	}
+	if rows[0]["score"] != int64(2) || rows[1]["score"] != int64(2) || rows[2]["score"] != int64(1) {
+		t.Fatalf("map: %v", rows)
+	}
+
+	// 排序与游标不一致
+	for _, sort := range []string{"score,id", "-score,-id", "id,score", "id"} {
+		var list []*pageItem
+		if _, err = Paginate(newDO(), &list, PageReq{Size: 3, Sort: sort, Cursor: res.Cursor}, id, score); !errors.Is(err, errno.BadRequest) {
+			t.Fatalf("%s: %v", sort, err)
+		}
+	}
+	var list []*pageItem
+	if _, err = Paginate(newDO(), &list, PageReq{Size: 3, Sort: "-score,id", Cursor: "x!"}, id, score); !errors.Is(err, errno.BadRequest) {
+		t.Fatalf("invalid cursor: %v", err)
+	}
+
+	// 页码方式
+	list = nil
+	if res, err = Paginate(newDO(), &list, PageReq{Page: 2, Size: 3, Sort: "id"}, id); err != nil || res.Page != 2 || len(list) != 3 || list[0].ID != 4 {
+		t.Fatalf("page: %+v %v", res, err)
+	}
+}
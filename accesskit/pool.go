@@ -0,0 +1,113 @@
+package accesskit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+	"time"
+)
+
+// 连接池设置与状态
+
+// DbStats 连接池状态
+type DbStats struct {
+	Primary  sql.DBStats   `json:"primary"`            // 主库
+	Replicas []sql.DBStats `json:"replicas,omitempty"` // 从库
+}
+
+// eachPool 遍历主库与从库的连接池
+func eachPool(db *gorm.DB, fc func(pool *sql.DB, replica bool) error) error {
+	primary, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err = fc(primary, false); err != nil {
+		return err
+	}
+	dr, ok := db.Config.Plugins[(&dbresolver.DBResolver{}).Name()].(*dbresolver.DBResolver)
+	if !ok {
+		return nil
+	}
+	return dr.Call(func(connPool gorm.ConnPool) error {
+		if pool, ok := connPool.(*sql.DB); ok && pool != primary {
+			return fc(pool, true)
+		}
+		return nil
+	})
+}
+
+// setupPool 按配置设置连接池
+func setupPool(db *gorm.DB, conf DbConfig) error {
+	return eachPool(db, func(pool *sql.DB, _ bool) error {
+		if conf.MaxOpenConns > 0 {
+			pool.SetMaxOpenConns(conf.MaxOpenConns)
+		}
+		if conf.MaxIdleConns > 0 {
+			pool.SetMaxIdleConns(conf.MaxIdleConns)
+		}
+		if conf.ConnMaxLifetime > 0 {
+			pool.SetConnMaxLifetime(time.Duration(conf.ConnMaxLifetime) * time.Second)
+		}
+		if conf.ConnMaxIdleTime > 0 {
+			pool.SetConnMaxIdleTime(time.Duration(conf.ConnMaxIdleTime) * time.Second)
+		}
+		return nil
+	})
+}
+
+// pingDb 检测连接，失败后按间隔翻倍重试
+func pingDb(db *gorm.DB, conf DbConfig) error {
+	if conf.PingAttempts <= 0 {
+		return nil
+	}
+	backoff := time.Duration(conf.PingBackoff) * time.Millisecond
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	var err error
+	for i := 0; i < conf.PingAttempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		err = eachPool(db, func(pool *sql.DB, replica bool) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if e1 := pool.PingContext(ctx); e1 != nil {
+				if replica {
+					return fmt.Errorf("从库连接失败: %w", e1)
+				}
+				return fmt.Errorf("主库连接失败: %w", e1)
+			}
+			return nil
+		})
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// closeDb 关闭所有连接
+func closeDb(db *gorm.DB) {
+	_ = eachPool(db, func(pool *sql.DB, _ bool) error {
+		_ = pool.Close()
+		return nil
+	})
+}
+
+// Stats 连接池的使用情况，可用于健康检查
+func Stats(db *gorm.DB) (DbStats, error) {
+	var stats DbStats
+	err := eachPool(db, func(pool *sql.DB, replica bool) error {
+		if replica {
+			stats.Replicas = append(stats.Replicas, pool.Stats())
+		} else {
+			stats.Primary = pool.Stats()
+		}
+		return nil
+	})
+	return stats, err
+}
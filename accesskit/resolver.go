@@ -0,0 +1,77 @@
+package accesskit
+
+import (
+	"fmt"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+	"strings"
+	"sync/atomic"
+)
+
+// 读写分离，依赖:
+//
+//	gorm.io/plugin/dbresolver
+//
+// SELECT 走从库；写操作、事务、SELECT ... FOR UPDATE 走主库
+
+// roundRobinPolicy 从库轮询
+type roundRobinPolicy struct {
+	n uint64
+}
+
+func (p *roundRobinPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	i := atomic.AddUint64(&p.n, 1)
+	return connPools[(i-1)%uint64(len(connPools))]
+}
+
+// resolverPolicy 根据配置名称选择负载均衡策略
+func resolverPolicy(name string) (dbresolver.Policy, error) {
+	switch strings.ToLower(name) {
+	case "", "random":
+		return dbresolver.RandomPolicy{}, nil
+	case "round_robin", "roundrobin", "rr":
+		return &roundRobinPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的负载均衡策略: [%s]", name)
+	}
+}
+
+// useReplicas 注册从库
+func useReplicas(db *gorm.DB, conf DbConfig) error {
+	if len(conf.Replicas) == 0 {
+		return nil
+	}
+	policy, err := resolverPolicy(conf.Policy)
+	if err != nil {
+		return err
+	}
+	replicas := make([]gorm.Dialector, 0, len(conf.Replicas))
+	for _, dsn := range conf.Replicas {
+		d, err := openDialector(conf.Dialect, dsn)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, d)
+	}
+	return db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   policy,
+	}))
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// UsePrimary 强制走主库，用于写后立即读的场景
+//
+//	示例:
+//
+//	   accesskit.UsePrimary(db).First(&user)
+func UsePrimary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write)
+}
+
+// UsePrimaryDO 同 UsePrimary ，用于 gorm/gen 的查询对象
+func UsePrimaryDO(do *gen.DO) {
+	do.ReplaceDB(UsePrimary(do.UnderlyingDB()))
+}
@@ -0,0 +1,342 @@
+package accesskit
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// 租户、审计字段与软删除，在 NewDb 中注册
+//
+//	只对包含对应字段的 model 生效；没有 model 的查询（如 db.Table("x")、Raw）不处理
+//
+//	tenant_id   查询、更新、删除时限定 tenant_id = ? ；创建时自动填充
+//	created_by  创建时填充
+//	updated_by  创建、更新时填充
+//	deleted_at  查询时过滤已删除的记录；删除时改为更新 deleted_at 。
+//	            gorm.DeletedAt 总是软删除；其它类型（如 *time.Time）需要配置 SoftDelete
+//	deleted_by  软删除时填充
+//
+//	示例:
+//
+//	   ctx = accesskit.WithTenant(ctx, tenantID)
+//	   ctx = accesskit.WithOperator(ctx, userID)
+//	   db.WithContext(ctx).Create(&order)
+//
+//	   // 跨租户
+//	   accesskit.IgnoreTenant(db.WithContext(ctx)).Find(&orders)
+//
+//	查询、更新、删除带租户字段的表时， context 中需要有租户，否则返回 ErrMissingTenant ；
+//	只能通过 IgnoreTenant 、 WithoutTenant 跨租户。
+//	更新、删除没有条件（WHERE 或主键）时与 gorm 一致返回 gorm.ErrMissingWhereClause ，租户与软删除的条件不计入
+
+// ScopeConfig 租户与审计字段的配置，字段名为空时使用默认值
+type ScopeConfig struct {
+	Disabled bool `json:"disabled" toml:"disabled"` // 关闭
+
+	TenantColumn    string `json:"tenant_column" toml:"tenant_column"`         // 默认 tenant_id
+	CreatedByColumn string `json:"created_by_column" toml:"created_by_column"` // 默认 created_by
+	UpdatedByColumn string `json:"updated_by_column" toml:"updated_by_column"` // 默认 updated_by
+	DeletedByColumn string `json:"deleted_by_column" toml:"deleted_by_column"` // 默认 deleted_by
+	DeletedAtColumn string `json:"deleted_at_column" toml:"deleted_at_column"` // 默认 deleted_at
+
+	// 对不是 gorm.DeletedAt 的 deleted_at 字段也使用软删除。默认只处理 gorm.DeletedAt
+	SoftDelete bool `json:"soft_delete" toml:"soft_delete"`
+}
+
+// ErrMissingTenant 查询、更新、删除带租户字段的表，但 context 中没有租户
+var ErrMissingTenant = errors.New("缺少租户信息")
+
+type (
+	tenantKey     struct{}
+	operatorKey   struct{}
+	skipTenantKey struct{}
+)
+
+const ignoreTenantSetting = "accesskit:ignore_tenant"
+
+// WithTenant 在 context 中保存租户
+func WithTenant(ctx context.Context, tenantID interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// WithOperator 在 context 中保存当前用户，用于审计字段
+func WithOperator(ctx context.Context, userID interface{}) context.Context {
+	return context.WithValue(ctx, operatorKey{}, userID)
+}
+
+// WithoutTenant 该 context 下的操作不限定租户
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTenantKey{}, true)
+}
+
+// TenantFrom 取出租户
+func TenantFrom(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	v := ctx.Value(tenantKey{})
+	return v, v != nil
+}
+
+// OperatorFrom 取出当前用户
+func OperatorFrom(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	v := ctx.Value(operatorKey{})
+	return v, v != nil
+}
+
+// IgnoreTenant 本次操作不限定租户
+func IgnoreTenant(db *gorm.DB) *gorm.DB {
+	return db.Set(ignoreTenantSetting, true)
+}
+
+// IgnoreTenantDO 同 IgnoreTenant ，用于 gorm/gen 的查询对象
+func IgnoreTenantDO(do *gen.DO) {
+	do.ReplaceDB(IgnoreTenant(do.UnderlyingDB()))
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// ScopePlugin gorm 插件
+type ScopePlugin struct {
+	conf ScopeConfig
+}
+
+// NewScopePlugin 创建插件
+func NewScopePlugin(conf ScopeConfig) *ScopePlugin {
+	if conf.TenantColumn == "" {
+		conf.TenantColumn = "tenant_id"
+	}
+	if conf.CreatedByColumn == "" {
+		conf.CreatedByColumn = "created_by"
+	}
+	if conf.UpdatedByColumn == "" {
+		conf.UpdatedByColumn = "updated_by"
+	}
+	if conf.DeletedByColumn == "" {
+		conf.DeletedByColumn = "deleted_by"
+	}
+	if conf.DeletedAtColumn == "" {
+		conf.DeletedAtColumn = "deleted_at"
+	}
+	return &ScopePlugin{conf: conf}
+}
+
+func (p *ScopePlugin) Name() string {
+	return "accesskit:scope"
+}
+
+func (p *ScopePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("accesskit:scope_create", p.beforeCreate); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("accesskit:scope_query", p.beforeQuery); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("accesskit:scope_row", p.beforeQuery); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("accesskit:scope_update", p.beforeUpdate); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("accesskit:scope_delete", p.beforeDelete)
+}
+
+func (p *ScopePlugin) field(stmt *gorm.Statement, name string) *schema.Field {
+	if stmt.Schema == nil {
+		return nil
+	}
+	return stmt.Schema.LookUpField(name)
+}
+
+// tenant 需要限定的租户。 ok 为 false 时不限定；没有租户时添加 ErrMissingTenant 错误
+func (p *ScopePlugin) tenant(db *gorm.DB) (tenant interface{}, ok bool) {
+	stmt := db.Statement
+	if p.field(stmt, p.conf.TenantColumn) == nil {
+		return nil, false
+	}
+	if v, _ := db.Get(ignoreTenantSetting); v == true {
+		return nil, false
+	}
+	if v, _ := stmt.Context.Value(skipTenantKey{}).(bool); v {
+		return nil, false
+	}
+	if tenant, ok = TenantFrom(stmt.Context); !ok {
+		_ = db.AddError(ErrMissingTenant)
+	}
+	return
+}
+
+// softDeleteField 软删除的字段： gorm.DeletedAt ，或配置了 SoftDelete 时的 deleted_at
+func (p *ScopePlugin) softDeleteField(stmt *gorm.Statement) *schema.Field {
+	f := p.field(stmt, p.conf.DeletedAtColumn)
+	if f == nil || (!p.conf.SoftDelete && !isGormSoftDelete(f)) {
+		return nil
+	}
+	return f
+}
+
+// addConditions 增加租户与软删除的条件
+func (p *ScopePlugin) addConditions(db *gorm.DB) {
+	stmt := db.Statement
+	if tenant, ok := p.tenant(db); ok {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: p.conf.TenantColumn}, Value: tenant},
+		}})
+	}
+	// gorm.DeletedAt 由 gorm 处理
+	if f := p.softDeleteField(stmt); f != nil && !isGormSoftDelete(f) && !stmt.Unscoped {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: nil},
+		}})
+	}
+}
+
+// checkWhere 更新、删除需要调用方的条件： WHERE 或 model 的主键；在添加租户与软删除的条件之前检查
+func (p *ScopePlugin) checkWhere(db *gorm.DB) bool {
+	if db.AllowGlobalUpdate {
+		return true
+	}
+	stmt := db.Statement
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if w, ok := c.Expression.(clause.Where); ok && len(w.Exprs) > 0 {
+			return true
+		}
+	}
+	if stmt.Schema != nil && len(stmt.Schema.PrimaryFields) > 0 {
+		for _, v := range []interface{}{stmt.Model, stmt.Dest} {
+			rv := reflect.Indirect(reflect.ValueOf(v))
+			if v == nil || rv.Kind() == reflect.Map {
+				continue
+			}
+			if _, values := schema.GetIdentityFieldValuesMap(stmt.Context, rv, stmt.Schema.PrimaryFields); len(values) > 0 {
+				return true
+			}
+		}
+	}
+	_ = db.AddError(gorm.ErrMissingWhereClause)
+	return false
+}
+
+// isGormSoftDelete 是否为 gorm 自带的软删除字段（如 gorm.DeletedAt）
+func isGormSoftDelete(f *schema.Field) bool {
+	_, ok := reflect.New(f.FieldType).Interface().(schema.QueryClausesInterface)
+	return ok
+}
+
+func (p *ScopePlugin) beforeQuery(db *gorm.DB) {
+	if db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	p.addConditions(db)
+}
+
+func (p *ScopePlugin) beforeCreate(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	stmt := db.Statement
+	if f := p.field(stmt, p.conf.TenantColumn); f != nil {
+		if tenant, ok := TenantFrom(stmt.Context); ok {
+			p.setIfZero(stmt, f, tenant)
+		}
+	}
+	if op, ok := OperatorFrom(stmt.Context); ok {
+		if f := p.field(stmt, p.conf.CreatedByColumn); f != nil {
+			p.setIfZero(stmt, f, op)
+		}
+		if f := p.field(stmt, p.conf.UpdatedByColumn); f != nil {
+			p.setIfZero(stmt, f, op)
+		}
+	}
+}
+
+// setIfZero 字段为空时填充，支持单个对象与切片
+func (p *ScopePlugin) setIfZero(stmt *gorm.Statement, f *schema.Field, value interface{}) {
+	rv := stmt.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if _, zero := f.ValueOf(stmt.Context, rv.Index(i)); zero {
+				_ = f.Set(stmt.Context, rv.Index(i), value)
+			}
+		}
+	case reflect.Struct:
+		if _, zero := f.ValueOf(stmt.Context, rv); zero {
+			_ = f.Set(stmt.Context, rv, value)
+		}
+	case reflect.Map:
+		if m, ok := stmt.Dest.(map[string]interface{}); ok {
+			if _, ok = m[f.DBName]; !ok {
+				m[f.DBName] = value
+			}
+		}
+	}
+}
+
+func (p *ScopePlugin) beforeUpdate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	if !p.checkWhere(db) {
+		return
+	}
+	stmt := db.Statement
+	if op, ok := OperatorFrom(stmt.Context); ok {
+		if f := p.field(stmt, p.conf.UpdatedByColumn); f != nil {
+			stmt.SetColumn(f.DBName, op, true)
+		}
+	}
+	p.addConditions(db)
+}
+
+func (p *ScopePlugin) beforeDelete(db *gorm.DB) {
+	if db.Error != nil || db.Statement.SQL.Len() > 0 || !p.checkWhere(db) {
+		return
+	}
+	p.addConditions(db)
+	stmt := db.Statement
+	f := p.softDeleteField(stmt)
+	if f == nil || stmt.Unscoped || db.Error != nil {
+		return
+	}
+
+	// 改为 UPDATE ，参考 gorm.SoftDeleteDeleteClause
+	set := clause.Set{{Column: clause.Column{Name: f.DBName}, Value: stmt.DB.NowFunc()}}
+	if op, ok := OperatorFrom(stmt.Context); ok {
+		if fb := p.field(stmt, p.conf.DeletedByColumn); fb != nil {
+			set = append(set, clause.Assignment{Column: clause.Column{Name: fb.DBName}, Value: op})
+		}
+	}
+	stmt.AddClause(set)
+
+	_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+	column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+	if len(values) > 0 {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+	}
+	if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
+		_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+		column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+	}
+	if isGormSoftDelete(f) {
+		// gorm.DeletedAt 的条件由 gorm 在查询时添加，这里需要补上
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: nil},
+		}})
+	}
+
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(stmt.DB.Callback().Update().Clauses...)
+}
@@ -0,0 +1,166 @@
+package accesskit
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type scopeOrder struct {
+	ID        uint
+	TenantID  int
+	Name      string
+	CreatedBy int
+	UpdatedBy int
+	DeletedAt *time.Time
+	DeletedBy int
+}
+
+func newScopeDb(t *testing.T, name string, conf ScopeConfig) *gorm.DB {
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:" + name + "?mode=memory&cache=shared", Scope: conf}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&scopeOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestScopePlugin(t *testing.T) {
+	db := newScopeDb(t, "scope", ScopeConfig{SoftDelete: true})
+	ctx1 := WithOperator(WithTenant(context.Background(), 1), 9)
+	ctx2 := WithTenant(context.Background(), 2)
+
+	// 创建时填充租户与审计字段
+	a, b := &scopeOrder{Name: "a"}, &scopeOrder{Name: "b"}
+	if err := db.WithContext(ctx1).Create([]*scopeOrder{a, b}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if a.TenantID != 1 || a.CreatedBy != 9 || a.UpdatedBy != 9 {
+		t.Fatalf("create: %+v", a)
+	}
+	if err := db.WithContext(ctx2).Create(&scopeOrder{Name: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(tx *gorm.DB) int64 {
+		var n int64
+		if err := tx.Model(&scopeOrder{}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(db.WithContext(ctx1)); n != 2 {
+		t.Fatalf("tenant 1: %d", n)
+	}
+	if n := count(IgnoreTenant(db.WithContext(ctx1))); n != 3 {
+		t.Fatalf("IgnoreTenant: %d", n)
+	}
+	if n := count(db.WithContext(WithoutTenant(ctx1))); n != 3 {
+		t.Fatalf("WithoutTenant: %d", n)
+	}
+
+	// 更新限定在租户内，并填充 updated_by
+	if err := db.WithContext(WithOperator(ctx2, 7)).Model(&scopeOrder{}).Where("name <> ?", "").Update("name", "x").Error; err != nil {
+		t.Fatal(err)
+	}
+	var list []scopeOrder
+	IgnoreTenant(db).Order("id").Find(&list)
+	if list[0].Name != "a" || list[2].Name != "x" || list[2].UpdatedBy != 7 {
+		t.Fatalf("update: %+v", list)
+	}
+
+	// 没有条件的更新、删除
+	if err := db.WithContext(ctx1).Model(&scopeOrder{}).Update("name", "z").Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("global update: %v", err)
+	}
+	if err := db.WithContext(ctx1).Delete(&scopeOrder{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("global delete: %v", err)
+	}
+	if err := db.WithContext(ctx1).Unscoped().Delete(&scopeOrder{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("global unscoped delete: %v", err)
+	}
+	if n := count(IgnoreTenant(db)); n != 3 {
+		t.Fatalf("after global delete: %d", n)
+	}
+	if err := db.WithContext(ctx1).Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&scopeOrder{}).Update("name", "y").Error; err != nil {
+		t.Fatal(err)
+	}
+	IgnoreTenant(db).Order("id").Find(&list)
+	if list[0].Name != "y" || list[1].Name != "y" || list[2].Name != "x" {
+		t.Fatalf("AllowGlobalUpdate: %+v", list)
+	}
+
+	// 软删除
+	if err := db.WithContext(ctx1).Delete(a).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := count(db.WithContext(ctx1)); n != 1 {
+		t.Fatalf("soft delete: %d", n)
+	}
+	var deleted scopeOrder
+	db.WithContext(ctx1).Unscoped().First(&deleted, a.ID)
+	if deleted.DeletedAt == nil || deleted.DeletedBy != 9 {
+		t.Fatalf("deleted: %+v", deleted)
+	}
+	// 其它租户的记录不会被删除
+	if err := db.WithContext(ctx2).Delete(&scopeOrder{}, b.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := count(db.WithContext(ctx1)); n != 1 {
+		t.Fatalf("cross tenant delete: %d", n)
+	}
+
+	// gen.DO 的 Select / SelectAppend
+	var do gen.DO
+	do.UseDB(db)
+	do.UseModel(&scopeOrder{})
+	q := do.WithContext(ctx1).(*gen.DO)
+	Select(q, "name")
+	SelectAppend(q, "id")
+	var rows []map[string]interface{}
+	if err := q.Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["name"] != "y" {
+		t.Fatalf("Select: %+v", rows)
+	}
+}
+
+func TestScopeMissingTenant(t *testing.T) {
+	db := newScopeDb(t, "scope_missing", ScopeConfig{})
+	ctx := WithTenant(context.Background(), 1)
+	a := &scopeOrder{Name: "a"}
+	if err := db.WithContext(ctx).Create(a).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有租户时不执行
+	var list []scopeOrder
+	if err := db.Find(&list).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("query: %v", err)
+	}
+	if err := db.Model(&scopeOrder{}).Where("id = ?", a.ID).Update("name", "x").Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("update: %v", err)
+	}
+	if err := db.Delete(&scopeOrder{}, a.ID).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := IgnoreTenant(db).Find(&list).Error; err != nil || len(list) != 1 || list[0].Name != "a" {
+		t.Fatalf("IgnoreTenant: %v %v", list, err)
+	}
+
+	// 没有配置 SoftDelete 时， *time.Time 的 deleted_at 仍为物理删除
+	if err := db.WithContext(ctx).Delete(a).Error; err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	IgnoreTenant(db).Unscoped().Model(&scopeOrder{}).Count(&n)
+	if n != 0 {
+		t.Fatalf("hard delete: %d", n)
+	}
+}
@@ -0,0 +1,125 @@
+package accesskit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/xtulnx/go-srv/errno"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 事务辅助
+//
+//	示例:
+//
+//	   err := accesskit.WithTx(ctx, db, func(tx *gorm.DB) error {
+//	       if err := tx.Create(&order).Error; err != nil {
+//	           return err
+//	       }
+//	       // 嵌套调用，使用 savepoint
+//	       _ = accesskit.WithTx(ctx, tx, func(tx2 *gorm.DB) error {
+//	           return tx2.Create(&log).Error
+//	       })
+//	       // 最外层事务提交后执行
+//	       accesskit.AfterCommit(tx, func(ctx context.Context) { cache.Del(order.ID) })
+//	       return nil
+//	   })
+
+type txStateKey struct{}
+
+// txState 事务内的状态，随 context 传递
+type txState struct {
+	hooks []func(ctx context.Context)
+}
+
+func getTxState(db *gorm.DB) *txState {
+	if db == nil || db.Statement == nil || db.Statement.Context == nil {
+		return nil
+	}
+	s, _ := db.Statement.Context.Value(txStateKey{}).(*txState)
+	return s
+}
+
+// AfterCommit 注册回调，在最外层事务提交后执行；事务回滚（包括 savepoint 回滚）时丢弃。
+// 如果 tx 不在 WithTx 的事务内，则立即执行
+func AfterCommit(tx *gorm.DB, fc func(ctx context.Context)) {
+	if s := getTxState(tx); s != nil {
+		s.hooks = append(s.hooks, fc)
+		return
+	}
+	ctx := context.Background()
+	if tx != nil && tx.Statement != nil && tx.Statement.Context != nil {
+		ctx = tx.Statement.Context
+	}
+	fc(ctx)
+}
+
+// WithTx 在事务内执行 fc 。如果 db 已经在事务内，则使用 savepoint
+//
+//	fc 返回错误或 panic 时回滚； panic 转成 errno.FailedUpdate 的错误
+func WithTx(ctx context.Context, db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	return WithTxRetry(ctx, db, 0, fc)
+}
+
+// WithTxRetry 同 WithTx ，遇到死锁、序列化冲突时，最多重试 retries 次（只在最外层事务重试）
+func WithTxRetry(ctx context.Context, db *gorm.DB, retries int, fc func(tx *gorm.DB) error) error {
+	if s := getTxState(db); s != nil {
+		// 嵌套事务
+		n := len(s.hooks)
+		err := runTx(db.WithContext(context.WithValue(ctx, txStateKey{}, s)), fc)
+		if err != nil {
+			s.hooks = s.hooks[:n]
+		}
+		return err
+	}
+
+	backoff := 50 * time.Millisecond
+	for i := 0; ; i++ {
+		s := &txState{}
+		err := runTx(db.WithContext(context.WithValue(ctx, txStateKey{}, s)), fc)
+		if err == nil {
+			for _, h := range s.hooks {
+				h(ctx)
+			}
+			return nil
+		}
+		if i >= retries || !IsRetryableTxErr(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// runTx 执行事务，捕获 panic
+func runTx(db *gorm.DB, fc func(tx *gorm.DB) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			// gorm 已经回滚
+			err = errno.FailedUpdate.WithErr(fmt.Errorf("事务 panic: %v", r))
+		}
+	}()
+	return db.Transaction(fc)
+}
+
+// IsRetryableTxErr 是否为可重试的事务错误：死锁、锁等待超时、序列化冲突
+func IsRetryableTxErr(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		// 1213 ER_LOCK_DEADLOCK, 1205 ER_LOCK_WAIT_TIMEOUT
+		return me.Number == 1213 || me.Number == 1205
+	}
+	var se interface{ SQLState() string }
+	if errors.As(err, &se) {
+		// 40001 serialization_failure, 40P01 deadlock_detected
+		code := se.SQLState()
+		return code == "40001" || code == "40P01"
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package accesskit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/xtulnx/go-srv/errno"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type txItem struct {
+	ID   uint
+	Name string
+}
+
+// sqlStateErr 带 SQLSTATE 的错误，如 pgconn.PgError
+type sqlStateErr string
+
+func (e sqlStateErr) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateErr) SQLState() string { return string(e) }
+
+func TestWithTx(t *testing.T) {
+	db, err := NewDb(DbConfig{Dialect: "sqlite", DSN: "file:tx?mode=memory&cache=shared"}, "t_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&txItem{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	names := func() []string {
+		var list []string
+		db.Model(&txItem{}).Order("id").Pluck("name", &list)
+		return list
+	}
+	errInner := errors.New("inner")
+
+	// savepoint 回滚时，外层事务仍然提交；只执行外层注册的回调
+	var hooks []string
+	err = WithTx(ctx, db, func(tx *gorm.DB) error {
+		if err := tx.Create(&txItem{Name: "a"}).Error; err != nil {
+			return err
+		}
+		AfterCommit(tx, func(context.Context) { hooks = append(hooks, "a") })
+		if err := WithTx(ctx, tx, func(tx2 *gorm.DB) error {
+			tx2.Create(&txItem{Name: "b"})
+			AfterCommit(tx2, func(context.Context) { hooks = append(hooks, "b") })
+			return errInner
+		}); !errors.Is(err, errInner) {
+			t.Fatalf("inner: %v", err)
+		}
+		if err := WithTx(ctx, tx, func(tx2 *gorm.DB) error {
+			AfterCommit(tx2, func(context.Context) { hooks = append(hooks, "c") })
+			return tx2.Create(&txItem{Name: "c"}).Error
+		}); err != nil {
+			t.Fatalf("inner: %v", err)
+		}
+		if len(hooks) > 0 {
+			t.Fatalf("hooks before commit: %v", hooks)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(names()); got != "[a c]" {
+		t.Fatalf("savepoint rows: %s", got)
+	}
+	if got := fmt.Sprint(hooks); got != "[a c]" {
+		t.Fatalf("hooks: %s", got)
+	}
+
+	// 外层回滚时不执行回调
+	hooks = nil
+	err = WithTx(ctx, db, func(tx *gorm.DB) error {
+		tx.Create(&txItem{Name: "d"})
+		AfterCommit(tx, func(context.Context) { hooks = append(hooks, "d") })
+		return errInner
+	})
+	if !errors.Is(err, errInner) || len(hooks) > 0 || len(names()) != 2 {
+		t.Fatalf("rollback: %v %v %v", err, hooks, names())
+	}
+
+	// panic 转为错误并回滚
+	err = WithTx(ctx, db, func(tx *gorm.DB) error {
+		tx.Create(&txItem{Name: "e"})
+		panic("boom")
+	})
+	if !errors.Is(err, errno.FailedUpdate) || len(names()) != 2 {
+		t.Fatalf("panic: %v %v", err, names())
+	}
+
+	// 不在事务内时立即执行
+	AfterCommit(db, func(context.Context) { hooks = append(hooks, "now") })
+	if len(hooks) != 1 {
+		t.Fatalf("no tx: %v", hooks)
+	}
+
+	// 可重试的错误
+	n := 0
+	err = WithTxRetry(ctx, db, 2, func(tx *gorm.DB) error {
+		if n++; n == 1 {
+			return &mysql.MySQLError{Number: 1213, Message: "deadlock"}
+		}
+		return tx.Create(&txItem{Name: "f"}).Error
+	})
+	if err != nil || n != 2 || len(names()) != 3 {
+		t.Fatalf("retry: %v %d", err, n)
+	}
+}
+
+func TestIsRetryableTxErr(t *testing.T) {
+	for err, want := range map[error]bool{
+		&mysql.MySQLError{Number: 1213}:                      true,
+		&mysql.MySQLError{Number: 1205}:                      true,
+		&mysql.MySQLError{Number: 1062}:                      false,
+		fmt.Errorf("x: %w", &mysql.MySQLError{Number: 1213}): true,
+		sqlStateErr("40001"):                                 true,
+		sqlStateErr("40P01"):                                 true,
+		sqlStateErr("23505"):                                 false,
+		errno.FailedUpdate.WithErr(sqlStateErr("40001")):     true,
+		errors.New("database is locked"):                     false,
+		fmt.Errorf("x: %w", gorm.ErrRecordNotFound):          false,
+	} {
+		if got := IsRetryableTxErr(err); got != want {
+			t.Errorf("IsRetryableTxErr(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
@@ -1,20 +1,30 @@
 package alioss
 
 import (
+	"context"
 	"fmt"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
-	"path/filepath"
+	"io"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// AliOss 阿里云 OSS
+//
+//	SDK 暂不支持 context ，参数 ctx 仅为与 ObjectStore 一致
 type AliOss struct {
-	cfg AliOssConfig
+	storeBase
 
 	client *oss.Client
+	creds  CredentialProvider // 临时授权，为空时使用配置中的 AccessKey
+
+	pubKeys *pubKeyCache // 回调签名的公钥
 }
 
 func NewClient(cfg AliOssConfig) (*AliOss, error) {
-	c := AliOss{cfg: cfg}
+	c := AliOss{storeBase: storeBase{cfg: cfg}, pubKeys: newPubKeyCache(cfg)}
 	err := c.init()
 	if err != nil {
 		return nil, err
@@ -37,66 +47,143 @@ func (A *AliOss) GetClient() (*oss.Client, error) {
 	return A.client, nil
 }
 
-// UploadKey 上传后的文件对象路径
-func (A *AliOss) UploadKey(subKey string) string {
-	objectName := filepath.Join(A.cfg.UploadDir, subKey)
-	return objectName
+func (A *AliOss) bucket() (*oss.Bucket, error) {
+	return A.client.Bucket(A.cfg.Bucket)
 }
 
 // UploadFile 上传文件到 OSS
-func (A *AliOss) UploadFile(localFile string, objectName string) error {
-	bucket, err := A.client.Bucket(A.cfg.Bucket)
+func (A *AliOss) UploadFile(localFile string, objectName string, opts ...UploadOption) error {
+	bucket, err := A.bucket()
 	if err != nil {
 		return err
 	}
-	err = bucket.PutObjectFromFile(objectName, localFile)
+	o := newUploadOptions(objectName, opts)
+	err = bucket.PutObjectFromFile(objectName, localFile, o.ossOptions()...)
 	return err
 }
 
-// GenUrl 完整的对外可访问的URL
-func (A *AliOss) GenUrl(key string) string {
-	if A.cfg.Host == "" {
-		return key
+// Upload 上传数据到 OSS
+func (A *AliOss) Upload(ctx context.Context, objectName string, r io.Reader, opts ...UploadOption) error {
+	bucket, err := A.bucket()
+	if err != nil {
+		return err
 	}
-	if key == "" {
-		return ""
+	o := newUploadOptions(objectName, opts)
+	ossOpts := o.ossOptions()
+	if o.Size > 0 {
+		ossOpts = append(ossOpts, oss.ContentLength(o.Size))
 	}
-	if strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://") {
-		return key
+	return bucket.PutObject(objectName, r, ossOpts...)
+}
+
+// UploadLarge 分片上传，记录断点，中断后再次调用时继续上传
+func (A *AliOss) UploadLarge(ctx context.Context, objectName string, localFile string, opts ...UploadOption) error {
+	bucket, err := A.bucket()
+	if err != nil {
+		return err
+	}
+	o := newUploadOptions(objectName, opts)
+	ossOpts := append(o.ossOptions(), oss.Routines(o.Concurrency))
+	if o.CheckpointDir != "" {
+		ossOpts = append(ossOpts, oss.CheckpointDir(true, o.CheckpointDir))
+	} else {
+		ossOpts = append(ossOpts, oss.Checkpoint(true, ""))
 	}
-	return PathJoin(A.cfg.Host, key)
+	return bucket.UploadFile(objectName, localFile, o.PartSize, ossOpts...)
 }
 
-func PathJoin(a, b string) string {
-	if a == "" {
-		return b
-	} else if b == "" {
-		return a
+// Download 下载，需要关闭
+func (A *AliOss) Download(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	bucket, err := A.bucket()
+	if err != nil {
+		return nil, err
 	}
-	cH := a[len(a)-1]
-	cK := b[0]
-	if cH == '/' && cK == '/' {
-		return a + b[1:]
+	return bucket.GetObject(objectName)
+}
+
+// Delete 删除
+func (A *AliOss) Delete(ctx context.Context, objectName string) error {
+	bucket, err := A.bucket()
+	if err != nil {
+		return err
 	}
-	if cH != '/' && cK != '/' {
-		return a + "/" + b
+	return bucket.DeleteObject(objectName)
+}
+
+// List 按前缀列出对象
+func (A *AliOss) List(ctx context.Context, prefix, marker string, maxKeys int) (ListResult, error) {
+	var res ListResult
+	bucket, err := A.bucket()
+	if err != nil {
+		return res, err
 	}
-	return a + b
+	opts := []oss.Option{oss.Prefix(prefix), oss.Marker(marker)}
+	if maxKeys > 0 {
+		opts = append(opts, oss.MaxKeys(maxKeys))
+	}
+	lor, err := bucket.ListObjects(opts...)
+	if err != nil {
+		return res, err
+	}
+	for _, o := range lor.Objects {
+		res.Objects = append(res.Objects, ObjectInfo{
+			Key:          o.Key,
+			Size:         o.Size,
+			ETag:         strings.Trim(o.ETag, `"`),
+			LastModified: o.LastModified,
+		})
+	}
+	res.NextMarker, res.IsTruncated = lor.NextMarker, lor.IsTruncated
+	return res, nil
 }
 
-// SplitKey 尝试分离对象路径
-func (A *AliOss) SplitKey(u string) (h, k string) {
-	if strings.HasPrefix(u, A.cfg.Host) {
-		return A.cfg.Host, u[len(A.cfg.Host)+1:]
+// Stat 对象信息
+func (A *AliOss) Stat(ctx context.Context, objectName string) (ObjectInfo, error) {
+	info := ObjectInfo{Key: objectName}
+	bucket, err := A.bucket()
+	if err != nil {
+		return info, err
 	}
-	return "", k
+	h, err := bucket.GetObjectDetailedMeta(objectName)
+	if err != nil {
+		return info, err
+	}
+	info.Size, _ = strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	info.ETag = strings.Trim(h.Get("ETag"), `"`)
+	info.ContentType = h.Get("Content-Type")
+	info.LastModified, _ = http.ParseTime(h.Get("Last-Modified"))
+	metaPrefix := strings.ToLower(oss.HTTPHeaderOssMetaPrefix)
+	for k := range h {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, metaPrefix) {
+			if info.Meta == nil {
+				info.Meta = map[string]string{}
+			}
+			info.Meta[lk[len(metaPrefix):]] = h.Get(k)
+		}
+	}
+	return info, nil
 }
 
-// GenUrlThumbnail 缩略图
-func (A *AliOss) GenUrlThumbnail(key string) string {
-	u := A.GenUrl(key)
-	if u == "" {
-		return u
+// Presign 生成带签名的临时地址
+func (A *AliOss) Presign(ctx context.Context, method string, objectName string, expire time.Duration) (string, error) {
+	bucket, err := A.bucket()
+	if err != nil {
+		return "", err
 	}
-	return u + "?x-oss-process=image/auto-orient,0/quality,Q_76/resize,h_192,w_192"
+	return bucket.SignURL(objectName, oss.HTTPMethod(strings.ToUpper(method)), int64(expire/time.Second))
+}
+
+// PathJoin 拼接地址与路径，中间只保留一个「/」
+func PathJoin(a, b string) string {
+	if a == "" {
+		return b
+	} else if b == "" {
+		return a
+	}
+	return strings.TrimRight(a, "/") + "/" + strings.TrimLeft(b, "/")
+}
+
+// GenUrlThumbnail 缩略图，可以在 ImageStyles 中配置 thumbnail
+func (A *AliOss) GenUrlThumbnail(key string) string {
+	return A.GenUrlStyle(key, "thumbnail")
 }
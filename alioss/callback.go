@@ -0,0 +1,115 @@
+package alioss
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/xtulnx/go-srv/errno"
+	"github.com/xtulnx/go-srv/ginkit"
+	"github.com/xtulnx/go-srv/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// 上传回调
+//
+//	g.POST("/oss/callback", cli.CallbackHandler(logger, func(c *gin.Context, res *alioss.CallbackResult) (interface{}, error) {
+//	    uid := res.Vars["uid"] // GetPolicyToken 的 callbackBody 中的 uid=${x:uid}
+//	    ...
+//	    return gin.H{"url": res.URL}, nil
+//	}))
+
+// CallbackResult 回调的内容，对应 GetPolicyToken 中的 callbackBody
+type CallbackResult struct {
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`  // 对象路径，即 filename
+	URL      string `json:"url"`  // 对外访问的地址，参考 GenUrl
+	Size     int64  `json:"size"` // 文件大小
+	MimeType string `json:"mime_type"`
+	ETag     string `json:"etag"`
+
+	// 图片信息，非图片时为空
+	Height int    `json:"height,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Format string `json:"format,omitempty"`
+
+	Vars map[string]string `json:"vars,omitempty"` // 其它自定义参数
+}
+
+// callbackFields GetPolicyToken 中固定的字段
+var callbackFields = map[string]bool{
+	"bucket": true, "filename": true, "size": true, "mimeType": true, "etag": true,
+	"height": true, "width": true, "format": true,
+}
+
+// ParseCallback 解析回调参数
+func ParseCallback(params url.Values) (*CallbackResult, error) {
+	res := &CallbackResult{
+		Bucket:   params.Get("bucket"),
+		Key:      params.Get("filename"),
+		MimeType: params.Get("mimeType"),
+		ETag:     params.Get("etag"),
+		Format:   params.Get("format"),
+	}
+	if res.Key == "" {
+		return nil, errno.BadRequest.SetMsg("回调缺少文件路径")
+	}
+	var err error
+	if s := params.Get("size"); s != "" {
+		if res.Size, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, errno.BadRequest.WithMsg("无效的文件大小", err)
+		}
+	}
+	if s := params.Get("height"); s != "" {
+		res.Height, _ = strconv.Atoi(s)
+	}
+	if s := params.Get("width"); s != "" {
+		res.Width, _ = strconv.Atoi(s)
+	}
+	for k := range params {
+		if callbackFields[k] {
+			continue
+		}
+		if res.Vars == nil {
+			res.Vars = map[string]string{}
+		}
+		res.Vars[k] = params.Get(k)
+	}
+	return res, nil
+}
+
+// VerifyCallbackResult 校验回调并解析
+func (A *AliOss) VerifyCallbackResult(logger utils.Logger, r *http.Request) (*CallbackResult, error) {
+	params, err := A.VerifyCallback(logger, r)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ParseCallback(params)
+	if err != nil {
+		return nil, err
+	}
+	res.URL = A.GenUrl(res.Key)
+	return res, nil
+}
+
+// CallbackFunc 处理回调，返回的数据放在 ginkit.Response 的 Data 中
+type CallbackFunc func(c *gin.Context, res *CallbackResult) (interface{}, error)
+
+// CallbackHandler 上传回调的处理
+//
+//	OSS 要求回调返回 200 及 JSON ，其内容会原样返回给上传的客户端：
+//	校验失败时返回 400 ， OSS 告知客户端回调失败，返回中只有错误码与描述，参考 errno.Localize ；
+//	fn 返回错误时仍为 200 ，由客户端根据 code 判断
+func (A *AliOss) CallbackHandler(logger utils.Logger, fn CallbackFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := A.VerifyCallbackResult(logger, c.Request)
+		if err != nil {
+			// 返回内容会经 OSS 给到客户端，原错误只记录日志
+			logger.Warnf("oss 回调校验失败: %v", err)
+			code, message := errno.Localize(err, ginkit.AcceptLanguage(c))
+			c.AbortWithStatusJSON(http.StatusBadRequest, ginkit.Response{Code: code, Message: message})
+			return
+		}
+		data, err := fn(c, res)
+		ginkit.SendResponseStatus(c, http.StatusOK, err, data)
+	}
+}
@@ -0,0 +1,144 @@
+package alioss
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/xtulnx/go-srv/errno"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newCallbackRequest 模拟 OSS 的回调请求
+func newCallbackRequest(t *testing.T, key *rsa.PrivateKey, pubURL, body string) *http.Request {
+	sum := md5.Sum([]byte("/callback\n" + body))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.MD5, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	r.Header.Set("x-oss-pub-key-url", base64.StdEncoding.EncodeToString([]byte(pubURL)))
+	r.Header.Set("authorization", base64.StdEncoding.EncodeToString(sign))
+	return r
+}
+
+func TestVerifyCallback(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	pubPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	calls := 0
+	var hosts []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		hosts = append(hosts, r.Host)
+		_, _ = w.Write(pubPem)
+	}))
+	defer srv.Close()
+	// 所有域名都连接到测试服务器
+	tr := srv.Client().Transport.(*http.Transport).Clone()
+	tr.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	tr.TLSClientConfig.ServerName = "example.com"
+
+	cfg := AliOssConfig{
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+		EndPoint:        "oss-cn-shenzhen.aliyuncs.com",
+		PubKeyHosts:     []string{strings.TrimPrefix(srv.URL, "https://")},
+	}
+	cli, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.SetHTTPClient(&http.Client{Transport: tr})
+	logger := logrus.New()
+	logger.SetOutput(&bytes.Buffer{})
+
+	for i := 0; i < 3; i++ {
+		// 参数不同的地址使用同一个缓存
+		params, err := cli.VerifyCallback(logger, newCallbackRequest(t, key, srv.URL+"/pub.pem?v="+strings.Repeat("x", i), "filename=a.png&size=10"))
+		if err != nil || params.Get("filename") != "a.png" {
+			t.Fatalf("verify: %v %v", params, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("cache: %d calls", calls)
+	}
+
+	// OSS 文档中的 http 地址，使用 https 获取
+	if _, err = cli.VerifyCallback(logger, newCallbackRequest(t, key, "http://gosspublic.alicdn.com/callback_pub_key_v1.pem", "filename=a.png")); err != nil {
+		t.Fatalf("aliyun pub key url: %v", err)
+	}
+	if calls != 2 || hosts[1] != "gosspublic.alicdn.com" {
+		t.Fatalf("aliyun pub key url: %d %v", calls, hosts)
+	}
+
+	// 篡改内容
+	r := newCallbackRequest(t, key, srv.URL+"/pub.pem", "filename=a.png")
+	r.Body = http.NoBody
+	if _, err = cli.VerifyCallback(logger, r); err == nil {
+		t.Fatal("expect signature error")
+	}
+
+	// 不允许的地址
+	for _, u := range []string{
+		"https://evil.example.com/pub.pem",
+		"https://evil.oss-cn-hangzhou.aliyuncs.com/pub.pem",
+		"http://gosspublic.alicdn.com.evil.com/callback_pub_key_v1.pem",
+		"ftp://gosspublic.alicdn.com/callback_pub_key_v1.pem",
+		"https://user@gosspublic.alicdn.com/callback_pub_key_v1.pem",
+	} {
+		if _, err = cli.pubKeys.get(context.Background(), u); err == nil || !strings.Contains(err.Error(), "不允许") {
+			t.Fatalf("expect host error: %s %v", u, err)
+		}
+	}
+
+	// gin
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.POST("/callback", cli.CallbackHandler(logger, func(c *gin.Context, res *CallbackResult) (interface{}, error) {
+		if res.Vars["uid"] == "0" {
+			return nil, errno.QueryNotFound
+		}
+		return res, nil
+	}))
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, newCallbackRequest(t, key, srv.URL+"/pub.pem", "filename=a.png&size=10&width=20&uid=7"))
+	var resp struct {
+		Code int
+		Data CallbackResult
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Code != 200 || resp.Data.Size != 10 || resp.Data.Width != 20 || resp.Data.Vars["uid"] != "7" {
+		t.Fatalf("handler: %d %s", w.Code, w.Body.String())
+	}
+	// fn 返回错误时仍为 200
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, newCallbackRequest(t, key, srv.URL+"/pub.pem", "filename=a.png&uid=0"))
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Code != errno.QueryNotFound.Code {
+		t.Fatalf("handler: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, newCallbackRequest(t, key, "http://evil.example.com/pub.pem", "filename=a.png"))
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "evil.example.com") {
+		t.Fatalf("handler: %d %s", w.Code, w.Body.String())
+	}
+}
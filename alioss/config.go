@@ -1,13 +1,31 @@
 package alioss
 
 type AliOssConfig struct {
+	Driver string // 存储类型: aliyun(默认) s3 local ，参考 NewStore
+
 	AccessKeyId     string
 	AccessKeySecret string //
-	EndPoint        string // 可以使用云端内网域名
+	EndPoint        string // 可以使用云端内网域名； s3 时为 minio 等服务的地址，如 https://minio.local:9000
 	Bucket          string //
 	UploadDir       string // 上传目录，如 j00/demo/
 	Host            string // 主机名称，格式为 bucketname.endpoint ，如 https://j00-demo.oss-cn-shenzhen.aliyuncs.com
 	Callback        string // 服务端授权上传时的回调地址（完整地址）
 
-	HostAlias []string // 额外名称
+	PubKeyHosts []string // 回调公钥额外允许的域名（完整匹配，统一通过 https 获取），参考 DefaultPubKeyHosts
+	PubKeyTTL   int      // 回调公钥的缓存时间（秒），默认 3600
+
+	HostAlias []string // 额外名称，如绑定的自定义域名、 CDN 域名
+
+	Private    bool // 私有 bucket ， GenUrl 返回带签名的临时地址
+	SignExpire int  // 签名地址的有效期（秒），默认 3600
+
+	ImageStyles map[string]string // 图片样式，名称 => 处理参数，如 avatar = "image/resize,m_fill,w_200,h_200" ，参考 GenUrlStyle
+
+	RoleArn        string // STS 扮演的角色，参考 NewSTSProvider
+	StsEndPoint    string // STS 地址，默认 sts.aliyuncs.com
+	StsDuration    int    // 临时授权有效期（秒），默认 3600 ，最小 900
+	StsSessionName string // 默认 upload
+
+	Region   string // s3 的区域，可以为空
+	LocalDir string // local 时文件保存的目录； Host 为对外访问的地址，如 http://127.0.0.1:8080/oss/
 }
@@ -0,0 +1,244 @@
+package alioss
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// 图片处理，生成 x-oss-process 参数
+//
+//	https://help.aliyun.com/document_detail/44688.html 图片处理参数
+//
+//	   p := alioss.NewImage().AutoOrient(true).Resize(alioss.ResizeFill, 200, 200).Format("webp").Quality(80)
+//	   u := cli.GenUrlProcess(key, p.String())
+//
+//	常用的样式可以配置在 AliOssConfig.ImageStyles ，按名称使用:
+//
+//	   ImageStyles = { avatar = "image/resize,m_fill,w_200,h_200", cover = "image/resize,w_750/quality,Q_80" }
+//	   u := cli.GenUrlStyle(key, "avatar")
+
+// 缩放模式
+const (
+	ResizeLfit  = "lfit"  // 等比缩放，限制在宽高之内
+	ResizeMfit  = "mfit"  // 等比缩放，覆盖宽高
+	ResizeFill  = "fill"  // 等比缩放后居中裁剪
+	ResizePad   = "pad"   // 等比缩放后填充
+	ResizeFixed = "fixed" // 强制宽高
+)
+
+// 位置，用于裁剪与水印
+const (
+	GravityNW     = "nw"
+	GravityNorth  = "north"
+	GravityNE     = "ne"
+	GravityWest   = "west"
+	GravityCenter = "center"
+	GravityEast   = "east"
+	GravitySW     = "sw"
+	GravitySouth  = "south"
+	GravitySE     = "se"
+)
+
+// ImageProcess 图片处理的步骤，按添加的顺序执行
+type ImageProcess struct {
+	ops []string
+}
+
+// NewImage 创建图片处理
+func NewImage() *ImageProcess {
+	return &ImageProcess{}
+}
+
+func (p *ImageProcess) add(action string, params ...string) *ImageProcess {
+	p.ops = append(p.ops, strings.Join(append([]string{action}, params...), ","))
+	return p
+}
+
+// AutoOrient 按 EXIF 信息自动旋转
+func (p *ImageProcess) AutoOrient(enable bool) *ImageProcess {
+	if enable {
+		return p.add("auto-orient", "1")
+	}
+	return p.add("auto-orient", "0")
+}
+
+// Resize 缩放， mode 为空时使用默认的 lfit ；宽高为 0 时不限制
+func (p *ImageProcess) Resize(mode string, width, height int) *ImageProcess {
+	var params []string
+	if mode != "" {
+		params = append(params, "m_"+mode)
+	}
+	if height > 0 {
+		params = append(params, "h_"+strconv.Itoa(height))
+	}
+	if width > 0 {
+		params = append(params, "w_"+strconv.Itoa(width))
+	}
+	return p.add("resize", params...)
+}
+
+// ResizePercent 按百分比缩放
+func (p *ImageProcess) ResizePercent(percent int) *ImageProcess {
+	return p.add("resize", "p_"+strconv.Itoa(percent))
+}
+
+// Crop 裁剪，从 gravity 位置偏移 x,y 开始；宽高为 0 时到边缘
+func (p *ImageProcess) Crop(gravity string, x, y, width, height int) *ImageProcess {
+	params := []string{"x_" + strconv.Itoa(x), "y_" + strconv.Itoa(y)}
+	if width > 0 {
+		params = append(params, "w_"+strconv.Itoa(width))
+	}
+	if height > 0 {
+		params = append(params, "h_"+strconv.Itoa(height))
+	}
+	if gravity != "" {
+		params = append(params, "g_"+gravity)
+	}
+	return p.add("crop", params...)
+}
+
+// Circle 内切圆
+func (p *ImageProcess) Circle(radius int) *ImageProcess {
+	return p.add("circle", "r_"+strconv.Itoa(radius))
+}
+
+// Rotate 顺时针旋转，角度 0-360
+func (p *ImageProcess) Rotate(degree int) *ImageProcess {
+	return p.add("rotate", strconv.Itoa(degree))
+}
+
+// Format 转换格式，如 jpg png webp
+func (p *ImageProcess) Format(format string) *ImageProcess {
+	return p.add("format", format)
+}
+
+// Quality 绝对质量 1-100
+func (p *ImageProcess) Quality(q int) *ImageProcess {
+	return p.add("quality", "Q_"+strconv.Itoa(q))
+}
+
+// Interlace 渐进显示，仅 jpg
+func (p *ImageProcess) Interlace() *ImageProcess {
+	return p.add("interlace", "1")
+}
+
+// Blur 模糊，半径与标准差 1-50
+func (p *ImageProcess) Blur(radius, sigma int) *ImageProcess {
+	return p.add("blur", "r_"+strconv.Itoa(radius), "s_"+strconv.Itoa(sigma))
+}
+
+// Watermark 水印的位置与样式
+type Watermark struct {
+	Gravity      string // 位置，默认右下 se
+	X, Y         int    // 边距
+	Transparency int    // 不透明度 0-100 ，默认 100
+
+	// 文字水印
+	Size  int    // 字号
+	Color string // 颜色，如 FFFFFF
+	Font  string // 字体，如 wqy-zenhei
+}
+
+func (w Watermark) params() []string {
+	var params []string
+	if w.Gravity != "" {
+		params = append(params, "g_"+w.Gravity)
+	}
+	if w.X > 0 {
+		params = append(params, "x_"+strconv.Itoa(w.X))
+	}
+	if w.Y > 0 {
+		params = append(params, "y_"+strconv.Itoa(w.Y))
+	}
+	if w.Transparency > 0 {
+		params = append(params, "t_"+strconv.Itoa(w.Transparency))
+	}
+	return params
+}
+
+// WatermarkText 文字水印
+func (p *ImageProcess) WatermarkText(text string, w Watermark) *ImageProcess {
+	params := []string{"text_" + urlSafeBase64(text)}
+	if w.Font != "" {
+		params = append(params, "type_"+urlSafeBase64(w.Font))
+	}
+	if w.Size > 0 {
+		params = append(params, "size_"+strconv.Itoa(w.Size))
+	}
+	if w.Color != "" {
+		params = append(params, "color_"+strings.TrimPrefix(w.Color, "#"))
+	}
+	return p.add("watermark", append(params, w.params()...)...)
+}
+
+// WatermarkImage 图片水印， object 为同一 bucket 内的完整对象路径，可以带处理参数，如 logo.png?x-oss-process=image/resize,P_20
+func (p *ImageProcess) WatermarkImage(object string, w Watermark) *ImageProcess {
+	return p.add("watermark", append([]string{"image_" + urlSafeBase64(object)}, w.params()...)...)
+}
+
+// Raw 追加原始的处理步骤，如 sharpen,100
+func (p *ImageProcess) Raw(op string) *ImageProcess {
+	p.ops = append(p.ops, op)
+	return p
+}
+
+// String x-oss-process 的值
+func (p *ImageProcess) String() string {
+	if len(p.ops) == 0 {
+		return ""
+	}
+	return "image/" + strings.Join(p.ops, "/")
+}
+
+func urlSafeBase64(s string) string {
+	return base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString([]byte(s))
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// thumbnailProcess 默认的缩略图，可以在 ImageStyles 中配置 thumbnail 覆盖
+var thumbnailProcess = NewImage().AutoOrient(false).Quality(76).Resize("", 192, 192).String()
+
+// StyleProcess 样式对应的处理参数:
+//
+//	ImageStyles 中的名称；以 image/ 或 style/ 开头时原样使用；其它作为 OSS 控制台中定义的样式名称
+func (A *AliOss) StyleProcess(style string) string {
+	if p, ok := A.cfg.ImageStyles[style]; ok {
+		return p
+	}
+	if style == "thumbnail" {
+		return thumbnailProcess
+	}
+	if style == "" || strings.HasPrefix(style, "image/") || strings.HasPrefix(style, "style/") {
+		return style
+	}
+	return "style/" + style
+}
+
+// GenUrlProcess 带图片处理参数的地址。配置 Private 时签名失败记录日志，参考 GenUrl
+func (A *AliOss) GenUrlProcess(key, process string) string {
+	if process == "" {
+		return A.GenUrl(key)
+	}
+	if A.cfg.Private {
+		u, err := A.SignUrl(key, WithProcess(process))
+		if err == nil {
+			return u
+		}
+		logSignErr(key, err)
+	}
+	u := A.storeBase.GenUrl(key)
+	if u == "" {
+		return u
+	}
+	if strings.Contains(u, "?") {
+		return u + "&x-oss-process=" + process
+	}
+	return u + "?x-oss-process=" + process
+}
+
+// GenUrlStyle 按样式名称生成地址，参考 StyleProcess
+func (A *AliOss) GenUrlStyle(key, style string) string {
+	return A.GenUrlProcess(key, A.StyleProcess(style))
+}
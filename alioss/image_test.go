@@ -0,0 +1,79 @@
+package alioss
+
+import (
+	"testing"
+)
+
+func TestImageProcess(t *testing.T) {
+	for name, c := range map[string]struct {
+		p    *ImageProcess
+		want string
+	}{
+		"empty":          {NewImage(), ""},
+		"auto-orient":    {NewImage().AutoOrient(true), "image/auto-orient,1"},
+		"resize":         {NewImage().Resize(ResizeFill, 200, 100), "image/resize,m_fill,h_100,w_200"},
+		"resize width":   {NewImage().Resize("", 200, 0), "image/resize,w_200"},
+		"resize percent": {NewImage().ResizePercent(50), "image/resize,p_50"},
+		"crop":           {NewImage().Crop(GravityCenter, 10, 20, 100, 50), "image/crop,x_10,y_20,w_100,h_50,g_center"},
+		"crop to edge":   {NewImage().Crop("", 0, 0, 0, 0), "image/crop,x_0,y_0"},
+		"circle":         {NewImage().Circle(100), "image/circle,r_100"},
+		"rotate":         {NewImage().Rotate(90), "image/rotate,90"},
+		"blur":           {NewImage().Blur(3, 2), "image/blur,r_3,s_2"},
+		"raw":            {NewImage().Raw("sharpen,100"), "image/sharpen,100"},
+		"chain": {
+			NewImage().AutoOrient(true).Resize(ResizeLfit, 750, 0).Format("webp").Quality(80).Interlace(),
+			"image/auto-orient,1/resize,m_lfit,w_750/format,webp/quality,Q_80/interlace,1",
+		},
+		"watermark text": {
+			NewImage().WatermarkText("版权所有", Watermark{Gravity: GravitySE, X: 10, Y: 10, Transparency: 50, Size: 24, Color: "#FFFFFF", Font: "wqy-zenhei"}),
+			"image/watermark,text_54mI5p2D5omA5pyJ,type_d3F5LXplbmhlaQ,size_24,color_FFFFFF,g_se,x_10,y_10,t_50",
+		},
+		"watermark image": {
+			NewImage().WatermarkImage("logo.png?x-oss-process=image/resize,P_20", Watermark{Gravity: GravityNW}),
+			"image/watermark,image_bG9nby5wbmc_eC1vc3MtcHJvY2Vzcz1pbWFnZS9yZXNpemUsUF8yMA,g_nw",
+		},
+	} {
+		if got := c.p.String(); got != c.want {
+			t.Errorf("%s = %q, want %q", name, got, c.want)
+		}
+	}
+}
+
+func TestStyleProcess(t *testing.T) {
+	// 与原来固定的缩略图参数一致
+	if thumbnailProcess != "image/auto-orient,0/quality,Q_76/resize,h_192,w_192" {
+		t.Fatalf("thumbnail = %q", thumbnailProcess)
+	}
+
+	cli, err := NewClient(AliOssConfig{
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+		EndPoint:        "oss-cn-shenzhen.aliyuncs.com",
+		Host:            "https://j00.oss-cn-shenzhen.aliyuncs.com",
+		ImageStyles:     map[string]string{"avatar": "image/resize,m_fill,w_200,h_200"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for style, want := range map[string]string{
+		"":                   "",
+		"avatar":             "image/resize,m_fill,w_200,h_200",
+		"thumbnail":          thumbnailProcess,
+		"image/resize,w_100": "image/resize,w_100",
+		"style/cover":        "style/cover",
+		"cover":              "style/cover",
+	} {
+		if got := cli.StyleProcess(style); got != want {
+			t.Errorf("StyleProcess(%q) = %q, want %q", style, got, want)
+		}
+	}
+	if u := cli.GenUrlThumbnail("a.png"); u != "https://j00.oss-cn-shenzhen.aliyuncs.com/a.png?x-oss-process="+thumbnailProcess {
+		t.Fatalf("thumbnail url: %s", u)
+	}
+
+	// ImageStyles 中的 thumbnail 覆盖默认值
+	cli.cfg.ImageStyles["thumbnail"] = "style/thumb"
+	if got := cli.StyleProcess("thumbnail"); got != "style/thumb" {
+		t.Fatalf("thumbnail override: %q", got)
+	}
+}
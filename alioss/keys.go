@@ -0,0 +1,187 @@
+package alioss
+
+import (
+	"github.com/xtulnx/go-srv/errno"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// 对象路径与地址的转换
+//
+//	对象路径统一使用「/」，不以「/」开头，不包含「..」
+//	地址可以是 Host 或 HostAlias 下的地址，包括带签名、图片处理参数的地址
+//
+//	   key, err := cli.ParseKey("https://cdn.example.com/j00/demo/a.png?x-oss-process=...") // j00/demo/a.png
+//
+//	更换 CDN 域名时，把旧域名加到 HostAlias ，再替换已保存的地址:
+//
+//	   urls = cli.RewriteURLs(urls, "https://new-cdn.example.com")
+
+// NormalizeKey 规范化对象路径：「\」转为「/」，去掉多余的「/」与「.」；包含「..」时报错
+func NormalizeKey(key string) (string, error) {
+	segs := strings.FieldsFunc(key, func(r rune) bool { return r == '/' || r == '\\' })
+	list := segs[:0]
+	for _, seg := range segs {
+		switch seg {
+		case ".":
+		case "..":
+			return "", errno.BadRequest.SetMsg("无效的对象路径: " + key)
+		default:
+			list = append(list, seg)
+		}
+	}
+	return strings.Join(list, "/"), nil
+}
+
+// JoinKey 拼接对象路径，使用「/」；sub 中的「..」不会跳出 dir
+//
+//	不校验「..」，外部传入的路径使用 NormalizeKey 或 UploadKey
+func JoinKey(dir, sub string) string {
+	sub = path.Clean("/" + strings.ReplaceAll(sub, "\\", "/"))
+	return strings.TrimPrefix(path.Join(strings.ReplaceAll(dir, "\\", "/"), sub), "/")
+}
+
+// joinSubKey 规范化 sub 后拼接到 dir 之下，参考 NormalizeKey ；包含「..」时报错
+func joinSubKey(dir, sub string) (string, error) {
+	k, err := NormalizeKey(sub)
+	if err != nil {
+		return "", err
+	}
+	return JoinKey(dir, k), nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// ParseKey 从地址或路径中取出对象路径；不属于配置的域名时报错
+func (A *storeBase) ParseKey(u string) (string, error) {
+	if !isURL(u) {
+		return NormalizeKey(u)
+	}
+	_, key, ok := A.matchHost(u)
+	if !ok {
+		return "", errno.BadRequest.SetMsg("未知的地址: " + u)
+	}
+	return NormalizeKey(key)
+}
+
+// SplitKey 尝试分离对象路径：属于配置的域名时返回域名与对象路径；否则返回 "", u
+func (A *storeBase) SplitKey(u string) (h, k string) {
+	if host, key, ok := A.matchHost(u); ok {
+		if k, err := NormalizeKey(key); err == nil {
+			return host, k
+		}
+	}
+	return "", u
+}
+
+// RewriteURL 把 Host 、 HostAlias 下的地址改为 to 域名，保留路径与参数；其它地址原样返回
+func (A *storeBase) RewriteURL(u, to string) string {
+	if _, rest, ok := A.splitURL(u); ok {
+		return PathJoin(to, rest)
+	}
+	return u
+}
+
+// RewriteURLs 批量替换地址，参考 RewriteURL
+func (A *storeBase) RewriteURLs(urls []string, to string) []string {
+	list := make([]string, len(urls))
+	for i, u := range urls {
+		list[i] = A.RewriteURL(u, to)
+	}
+	return list
+}
+
+// RewriteText 替换文本（如富文本）中出现的 Host 、 HostAlias 下的地址
+func (A *storeBase) RewriteText(text, to string) string {
+	to = strings.TrimRight(to, "/")
+	for _, h := range A.hosts() {
+		b := strings.TrimPrefix(strings.TrimPrefix(h, "http://"), "https://")
+		for _, scheme := range []string{"https://", "http://"} {
+			if scheme+b != to {
+				text = strings.ReplaceAll(text, scheme+b+"/", to+"/")
+			}
+		}
+	}
+	return text
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// hosts 配置的域名： Host 及 HostAlias ，带协议，不带「/」结尾
+func (A *storeBase) hosts() []string {
+	var list []string
+	for _, h := range append([]string{A.cfg.Host}, A.cfg.HostAlias...) {
+		if h == "" {
+			continue
+		}
+		if !isURL(h) {
+			scheme := "https://"
+			if strings.HasPrefix(A.cfg.Host, "http://") {
+				scheme = "http://"
+			}
+			h = scheme + h
+		}
+		list = append(list, strings.TrimRight(h, "/"))
+	}
+	return list
+}
+
+// normalizeHost 查找配置中对应的域名，忽略协议与结尾的「/」
+func (A *storeBase) normalizeHost(host string) (string, bool) {
+	want := hostOnly(host)
+	for _, h := range A.hosts() {
+		if hostOnly(h) == want {
+			if isURL(host) {
+				return strings.TrimRight(host, "/"), true
+			}
+			return h, true
+		}
+	}
+	return "", false
+}
+
+// splitURL 地址是否属于配置的域名（忽略协议与大小写），返回地址中的域名部分与剩余部分
+func (A *storeBase) splitURL(u string) (base, rest string, ok bool) {
+	i := strings.Index(u, "://")
+	if i < 0 || !isURL(u) {
+		return "", "", false
+	}
+	s := u[i+3:]
+	for _, h := range A.hosts() {
+		b := strings.TrimPrefix(strings.TrimPrefix(h, "http://"), "https://")
+		if len(s) < len(b) || !strings.EqualFold(s[:len(b)], b) {
+			continue
+		}
+		if len(s) == len(b) || strings.ContainsRune("/?#", rune(s[len(b)])) {
+			n := i + 3 + len(b)
+			return u[:n], u[n:], true
+		}
+	}
+	return "", "", false
+}
+
+// matchHost 完整地址是否属于配置的域名，返回域名与对象路径（去掉参数）
+func (A *storeBase) matchHost(u string) (host, key string, ok bool) {
+	host, rest, ok := A.splitURL(u)
+	if !ok {
+		return "", "", false
+	}
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	if p, err := url.PathUnescape(rest); err == nil {
+		rest = p
+	}
+	return host, strings.TrimPrefix(rest, "/"), true
+}
+
+func hostOnly(h string) string {
+	h = strings.TrimPrefix(strings.TrimPrefix(h, "http://"), "https://")
+	if i := strings.IndexByte(h, '/'); i >= 0 {
+		h = h[:i]
+	}
+	return strings.ToLower(h)
+}
@@ -0,0 +1,69 @@
+package alioss
+
+import "testing"
+
+func TestKeys(t *testing.T) {
+	s := &storeBase{cfg: AliOssConfig{
+		UploadDir: "j00/demo/",
+		Host:      "https://j00.oss-cn-shenzhen.aliyuncs.com",
+		HostAlias: []string{"cdn.example.com", "http://127.0.0.1:8080/oss/"},
+	}}
+
+	for in, want := range map[string]string{
+		"a/b.png":    "j00/demo/a/b.png",
+		"\\a\\b.png": "j00/demo/a/b.png",
+		"./a//b.png": "j00/demo/a/b.png",
+	} {
+		if got, err := s.UploadKeyE(in); err != nil || got != want || s.UploadKey(in) != want {
+			t.Errorf("UploadKeyE(%q) = %q %v, want %q", in, got, err, want)
+		}
+	}
+	for in, want := range map[string]string{
+		"../../x.png":      "j00/demo/x.png",
+		"a/../b.png":       "j00/demo/b.png",
+		"a\\..\\..\\b.png": "j00/demo/b.png",
+	} {
+		if got, err := s.UploadKeyE(in); err == nil {
+			t.Errorf("UploadKeyE(%q) = %q, want error", in, got)
+		}
+		// 兼容：不报错，不跳出 UploadDir
+		if got := s.UploadKey(in); got != want {
+			t.Errorf("UploadKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for in, want := range map[string]string{
+		"https://j00.oss-cn-shenzhen.aliyuncs.com/j00/a.png":                       "j00/a.png",
+		"https://J00.oss-cn-shenzhen.aliyuncs.com/j00/a.png?x-oss-process=image/x": "j00/a.png",
+		"https://cdn.example.com/j00/a%2Fb.png?Expires=1&Signature=x":              "j00/a/b.png",
+		"http://127.0.0.1:8080/oss/j00/a.png":                                      "j00/a.png",
+		"/j00//./a.png":                                                            "j00/a.png",
+	} {
+		if got, err := s.ParseKey(in); err != nil || got != want {
+			t.Errorf("ParseKey(%q) = %q %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"https://cdn.example.com.evil.com/a.png", "https://other.com/a.png", "a/../../b", "https://cdn.example.com/../a"} {
+		if got, err := s.ParseKey(in); err == nil {
+			t.Errorf("ParseKey(%q) = %q, want error", in, got)
+		}
+	}
+
+	if h, k := s.SplitKey("https://cdn.example.com/j00/a.png"); h != "https://cdn.example.com" || k != "j00/a.png" {
+		t.Errorf("SplitKey = %q %q", h, k)
+	}
+	if h, k := s.SplitKey("https://other.com/a.png"); h != "" || k != "https://other.com/a.png" {
+		t.Errorf("SplitKey miss = %q %q", h, k)
+	}
+
+	urls := s.RewriteURLs([]string{
+		"https://cdn.example.com/j00/a.png?x-oss-process=image/resize,w_10",
+		"https://other.com/a.png",
+	}, "https://new.example.com/")
+	if urls[0] != "https://new.example.com/j00/a.png?x-oss-process=image/resize,w_10" || urls[1] != "https://other.com/a.png" {
+		t.Errorf("RewriteURLs = %v", urls)
+	}
+	if got := s.RewriteText(`<img src="http://cdn.example.com/a.png">`, "https://new.example.com"); got != `<img src="https://new.example.com/a.png">` {
+		t.Errorf("RewriteText = %s", got)
+	}
+}
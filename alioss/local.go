@@ -0,0 +1,402 @@
+package alioss
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStore 本地目录，用于开发与测试
+//
+//	文件保存在 cfg.LocalDir ， cfg.Host 为对外访问的地址，需要把 LocalStore 挂到该地址:
+//
+//	   store, _ := alioss.NewLocalStore(cfg) // cfg.Host = "http://127.0.0.1:8080/oss/"
+//	   g.Any("/oss/*key", gin.WrapH(store))
+//
+//	支持与 OSS 相同的表单直传（GetPolicyToken），以及 Presign 生成的 GET/PUT 地址；不支持上传回调
+type LocalStore struct {
+	storeBase
+
+	root     string // 本地目录
+	basePath string // cfg.Host 的路径部分
+}
+
+// NewLocalStore 创建本地存储
+func NewLocalStore(cfg AliOssConfig) (*LocalStore, error) {
+	if cfg.LocalDir == "" {
+		return nil, fmt.Errorf("无效的本地目录")
+	}
+	root, err := filepath.Abs(cfg.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	L := &LocalStore{storeBase: storeBase{cfg: cfg}, root: root, basePath: "/"}
+	if u, e1 := url.Parse(cfg.Host); e1 == nil && u.Path != "" {
+		L.basePath = u.Path
+		if !strings.HasSuffix(L.basePath, "/") {
+			L.basePath += "/"
+		}
+	}
+	return L, nil
+}
+
+// filePath 对象对应的本地文件，不允许跳出目录
+func (L *LocalStore) filePath(objectName string) (string, error) {
+	key, err := NormalizeKey(objectName)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", fmt.Errorf("无效的对象路径: [%s]", objectName)
+	}
+	return filepath.Join(L.root, filepath.FromSlash(key)), nil
+}
+
+// UploadFile 上传本地文件
+func (L *LocalStore) UploadFile(localFile string, objectName string, opts ...UploadOption) error {
+	return L.UploadLarge(context.Background(), objectName, localFile, opts...)
+}
+
+// UploadLarge 复制本地文件
+func (L *LocalStore) UploadLarge(ctx context.Context, objectName string, localFile string, opts ...UploadOption) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if st, e1 := f.Stat(); e1 == nil {
+		opts = append([]UploadOption{WithSize(st.Size())}, opts...)
+	}
+	return L.Upload(ctx, objectName, f, opts...)
+}
+
+// Upload 上传数据，先写临时文件再改名
+func (L *LocalStore) Upload(ctx context.Context, objectName string, r io.Reader, opts ...UploadOption) error {
+	o := newUploadOptions(objectName, opts)
+	r = o.wrapProgress(r)
+	p, err := L.filePath(objectName)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if e1 := tmp.Close(); err == nil {
+		err = e1
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Download 下载，需要关闭
+func (L *LocalStore) Download(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	p, err := L.filePath(objectName)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Delete 删除，不存在时不报错
+func (L *LocalStore) Delete(ctx context.Context, objectName string) error {
+	p, err := L.filePath(objectName)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List 按前缀列出对象，按路径排序
+func (L *LocalStore) List(ctx context.Context, prefix, marker string, maxKeys int) (ListResult, error) {
+	var res ListResult
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	var keys []string
+	err := filepath.WalkDir(L.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(L.root, p)
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			// 跳过与前缀无关的目录
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(key, prefix) && key > marker && !strings.HasPrefix(d.Name(), ".upload-") {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+	sort.Strings(keys)
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		res.IsTruncated = true
+		res.NextMarker = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		info, err := L.Stat(ctx, k)
+		if err != nil {
+			return res, err
+		}
+		res.Objects = append(res.Objects, info)
+	}
+	return res, nil
+}
+
+// Stat 对象信息， ETag 为文件的 md5
+func (L *LocalStore) Stat(ctx context.Context, objectName string) (ObjectInfo, error) {
+	info := ObjectInfo{Key: objectName}
+	p, err := L.filePath(objectName)
+	if err != nil {
+		return info, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return info, err
+	}
+	if st.IsDir() {
+		return info, fs.ErrNotExist
+	}
+	h := md5.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return info, err
+	}
+	info.Size = st.Size()
+	info.LastModified = st.ModTime()
+	info.ETag = strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+	info.ContentType = mime.TypeByExtension(path.Ext(objectName))
+	return info, nil
+}
+
+// Presign 生成带签名的临时地址，由 ServeHTTP 校验
+func (L *LocalStore) Presign(ctx context.Context, method string, objectName string, expire time.Duration) (string, error) {
+	method = strings.ToUpper(method)
+	expires := strconv.FormatInt(time.Now().Add(expire).Unix(), 10)
+	q := url.Values{}
+	q.Set("Expires", expires)
+	q.Set("Signature", L.sign(method, objectName, expires))
+	return L.GenUrl(objectName) + "?" + q.Encode(), nil
+}
+
+// GetPolicyToken 获取 上传授权 token ，格式与 OSS 相同
+//
+// Deprecated: 失败时只记录日志并返回空的 token ，使用 GetPolicyTokenWith
+func (L *LocalStore) GetPolicyToken(prefix, callbackBody string) PolicyToken {
+	token, err := L.GetPolicyTokenWith(prefix, PolicyOptions{CallbackBody: callbackBody})
+	return policyTokenOrLog(token, err, prefix)
+}
+
+// GetPolicyTokenWith 按选项获取 上传授权 token
+func (L *LocalStore) GetPolicyTokenWith(prefix string, opts PolicyOptions) (PolicyToken, error) {
+	return newPolicyToken(L.cfg, prefix, opts)
+}
+
+func (L *LocalStore) sign(method, objectName, expires string) string {
+	h := hmac.New(sha1.New, []byte(L.cfg.AccessKeySecret))
+	_, _ = io.WriteString(h, method+"\n"+strings.TrimPrefix(objectName, "/")+"\n"+expires)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// checkSign 校验 Presign 的签名
+func (L *LocalStore) checkSign(r *http.Request, objectName string) bool {
+	q := r.URL.Query()
+	expires := q.Get("Expires")
+	if t, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().Unix() > t {
+		return false
+	}
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	return hmac.Equal([]byte(q.Get("Signature")), []byte(L.sign(method, objectName, expires)))
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// ServeHTTP 访问文件，以及处理上传:
+//
+//	GET/HEAD 读取文件，带签名时校验签名
+//	PUT      Presign 生成的上传地址
+//	POST     表单直传
+func (L *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, L.basePath)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if r.URL.Query().Get("Signature") != "" && !L.checkSign(r, key) {
+			http.Error(w, "签名无效", http.StatusForbidden)
+			return
+		}
+		p, err := L.filePath(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, p)
+	case http.MethodPut:
+		if !L.checkSign(r, key) {
+			http.Error(w, "签名无效", http.StatusForbidden)
+			return
+		}
+		if err := L.Upload(r.Context(), key, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPost:
+		L.servePost(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// servePost 表单直传，校验 policy 与签名
+func (L *LocalStore) servePost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form := map[string]string{}
+	for k, v := range r.MultipartForm.Value {
+		if len(v) > 0 {
+			form[strings.ToLower(k)] = v[0]
+		}
+	}
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	key := strings.ReplaceAll(form["key"], "${filename}", fh.Filename)
+	form["key"] = key
+
+	policy := form["policy"]
+	h := hmac.New(sha1.New, []byte(L.cfg.AccessKeySecret))
+	_, _ = io.WriteString(h, policy)
+	if form["ossaccesskeyid"] != L.cfg.AccessKeyId ||
+		!hmac.Equal([]byte(form["signature"]), []byte(base64.StdEncoding.EncodeToString(h.Sum(nil)))) {
+		http.Error(w, "签名无效", http.StatusForbidden)
+		return
+	}
+	if err = checkPolicy(policy, form, fh.Size); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err = L.Upload(r.Context(), key, file); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPolicy 校验表单是否满足 policy 的条件
+//
+//	支持: eq, starts-with, in, content-length-range
+func checkPolicy(policy string, form map[string]string, size int64) error {
+	b, err := base64.StdEncoding.DecodeString(policy)
+	if err != nil {
+		return err
+	}
+	var p struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}
+	if err = json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	if t, e1 := time.Parse(time.RFC3339, p.Expiration); e1 != nil || time.Now().After(t) {
+		return fmt.Errorf("policy 已过期")
+	}
+	for _, c := range p.Conditions {
+		switch v := c.(type) {
+		case map[string]interface{}:
+			for k, want := range v {
+				if form[strings.ToLower(k)] != fmt.Sprint(want) {
+					return fmt.Errorf("字段 %s 不符合要求", k)
+				}
+			}
+		case []interface{}:
+			if len(v) != 3 {
+				continue
+			}
+			op, _ := v[0].(string)
+			name, _ := v[1].(string)
+			name = strings.ToLower(strings.TrimPrefix(name, "$"))
+			switch strings.ToLower(op) {
+			case "eq":
+				if form[name] != fmt.Sprint(v[2]) {
+					return fmt.Errorf("字段 %s 不符合要求", name)
+				}
+			case "starts-with":
+				if !strings.HasPrefix(form[name], fmt.Sprint(v[2])) {
+					return fmt.Errorf("字段 %s 不符合要求", name)
+				}
+			case "in":
+				list, _ := v[2].([]interface{})
+				found := false
+				for _, a := range list {
+					if fmt.Sprint(a) == form[name] {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return fmt.Errorf("字段 %s 不符合要求", name)
+				}
+			case "content-length-range":
+				lo, _ := v[1].(float64)
+				hi, _ := v[2].(float64)
+				if size < int64(lo) || size > int64(hi) {
+					return fmt.Errorf("文件大小不符合要求")
+				}
+			}
+		}
+	}
+	return nil
+}
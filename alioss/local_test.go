@@ -0,0 +1,128 @@
+package alioss
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(nil)
+	defer srv.Close()
+
+	store, err := NewLocalStore(AliOssConfig{
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+		UploadDir:       "demo",
+		Host:            srv.URL + "/oss/",
+		LocalDir:        t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.Handler = store
+
+	// 表单直传
+	token := store.GetPolicyToken("a/", "")
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("key", token.Directory+"/${filename}")
+	_ = mw.WriteField("policy", token.Policy)
+	_ = mw.WriteField("OSSAccessKeyId", token.AccessKeyId)
+	_ = mw.WriteField("signature", token.Signature)
+	fw, _ := mw.CreateFormFile("file", "1.txt")
+	_, _ = fw.Write([]byte("hello"))
+	_ = mw.Close()
+	resp, err := http.Post(token.Host, mw.FormDataContentType(), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("post: %d", resp.StatusCode)
+	}
+
+	// 超出前缀
+	if err = checkPolicy(token.Policy, map[string]string{"key": "other/1.txt"}, 1); err == nil {
+		t.Fatal("expect policy error")
+	}
+
+	// 不能跳出上传目录
+	if _, err = store.GetPolicyTokenWith("../b/", PolicyOptions{}); err == nil {
+		t.Fatal("expect prefix traversal error")
+	}
+	if _, err = store.GetPolicyTokenWith("b/", PolicyOptions{Key: "../x.png"}); err == nil {
+		t.Fatal("expect key traversal error")
+	}
+
+	// 指定路径与类型
+	token, err = store.GetPolicyTokenWith("b/", PolicyOptions{Key: "x.png", ContentTypes: []string{"image/png"}, MaxSize: 10})
+	if err != nil || token.FileKey != "demo/b/x.png" {
+		t.Fatalf("token: %+v %v", token, err)
+	}
+	if err = checkPolicy(token.Policy, map[string]string{"key": "demo/b/x.png", "content-type": "image/png"}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkPolicy(token.Policy, map[string]string{"key": "demo/b/y.png", "content-type": "image/png"}, 10); err == nil {
+		t.Fatal("expect key error")
+	}
+	if err = checkPolicy(token.Policy, map[string]string{"key": "demo/b/x.png", "content-type": "text/plain"}, 10); err == nil {
+		t.Fatal("expect content-type error")
+	}
+	if err = checkPolicy(token.Policy, map[string]string{"key": "demo/b/x.png", "content-type": "image/png"}, 11); err == nil {
+		t.Fatal("expect size error")
+	}
+
+	// 签名地址
+	var consumed, total int64
+	err = UploadBytes(ctx, store, "demo/a/2.txt", []byte("world"), WithProgress(func(c, t int64) {
+		consumed, total = c, t
+	}))
+	if err != nil || consumed != 5 || total != 5 {
+		t.Fatalf("upload: %d/%d %v", consumed, total, err)
+	}
+	u, _ := store.Presign(ctx, "GET", "demo/a/2.txt", time.Minute)
+	resp, err = http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(b) != "world" {
+		t.Fatalf("get: %d %s", resp.StatusCode, b)
+	}
+	resp, _ = http.Get(u + "x")
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("bad sign: %d", resp.StatusCode)
+	}
+
+	res, err := store.List(ctx, "demo/a/", "", 1)
+	if err != nil || len(res.Objects) != 1 || !res.IsTruncated || res.Objects[0].Key != "demo/a/1.txt" {
+		t.Fatalf("list: %+v %v", res, err)
+	}
+	res, _ = store.List(ctx, "demo/a/", res.NextMarker, 10)
+	if len(res.Objects) != 1 || res.Objects[0].Key != "demo/a/2.txt" {
+		t.Fatalf("list next: %+v", res)
+	}
+
+	info, err := store.Stat(ctx, "demo/a/1.txt")
+	if err != nil || info.Size != 5 {
+		t.Fatalf("stat: %+v %v", info, err)
+	}
+	if err = store.Delete(ctx, "demo/a/1.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Stat(ctx, "demo/a/1.txt"); err == nil {
+		t.Fatal("expect not found")
+	}
+	if _, err = store.Download(ctx, "../../etc/passwd"); err == nil {
+		t.Fatal("expect error")
+	}
+}
@@ -0,0 +1,356 @@
+package alioss
+
+import (
+	"context"
+	"errors"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/xtulnx/go-srv/errno"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// 对象的管理，路径都在 UploadDir 之下，与 UploadKey 一致； AliOss 与 LocalStore 支持
+//
+//	参数与返回的 key 都是相对 UploadDir 的路径，错误为 errno:
+//	  QueryNotFound 对象不存在
+//	  Forbidden     无权限
+//	  QueryFailed   查询失败
+//	  FailedUpdate  删除、复制失败
+//
+//	   res, err := cli.ListObjects(ctx, "avatar/", "", 100)
+//	   err = cli.MoveObject(ctx, "tmp/a.png", "avatar/a.png")
+
+// maxDeleteBatch 每次批量删除的上限
+const maxDeleteBatch = 1000
+
+// ossErr 转为 errno ，def 为默认的错误码
+func ossErr(err error, def *errno.Errno) error {
+	if err == nil || errno.IsErrno(err) {
+		return err
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return errno.QueryNotFound.WithErr(err)
+	}
+	var se oss.ServiceError
+	if errors.As(err, &se) {
+		switch {
+		case se.StatusCode == http.StatusNotFound || se.Code == "NoSuchKey":
+			return errno.QueryNotFound.WithErr(err)
+		case se.StatusCode == http.StatusForbidden:
+			return errno.Forbidden.WithErr(err)
+		}
+	}
+	return def.WithErr(err)
+}
+
+// objectBackend 对象管理使用的底层操作，参数都是完整的对象路径
+type objectBackend interface {
+	Delete(ctx context.Context, objectName string) error
+	List(ctx context.Context, prefix, marker string, maxKeys int) (ListResult, error)
+	Stat(ctx context.Context, objectName string) (ObjectInfo, error)
+	// copyObject 复制， destBucket 已确定为其它 bucket 或为空（当前 bucket）
+	copyObject(ctx context.Context, destBucket, src, dest string) error
+	// deleteObjects 批量删除，返回已删除的完整路径
+	deleteObjects(ctx context.Context, keys []string) ([]string, error)
+	exists(ctx context.Context, key string) (bool, error)
+}
+
+// objects 对象管理的实现，由 AliOss 、 LocalStore 调用
+type objects struct {
+	base *storeBase
+	be   objectBackend
+}
+
+// scopedKey 相对路径转为完整的对象路径，不允许跳出 UploadDir
+func (A *storeBase) scopedKey(subKey string) (string, error) {
+	k, err := NormalizeKey(subKey)
+	if err != nil || k == "" {
+		return "", errno.BadRequest.SetMsg("无效的文件路径: " + subKey)
+	}
+	return JoinKey(A.cfg.UploadDir, k), nil
+}
+
+// relKey 完整的对象路径转为相对路径，不在 UploadDir 之下时原样返回
+func (A *storeBase) relKey(key string) string {
+	dir := JoinKey(A.cfg.UploadDir, "")
+	if dir == "" {
+		return key
+	}
+	if strings.HasPrefix(key, dir+"/") {
+		return key[len(dir)+1:]
+	}
+	return key
+}
+
+// sameBucket 是否为当前 bucket
+func (A *storeBase) sameBucket(bucket string) bool {
+	return bucket == "" || bucket == A.cfg.Bucket
+}
+
+func (o objects) DeleteObject(ctx context.Context, subKey string) error {
+	key, err := o.base.scopedKey(subKey)
+	if err != nil {
+		return err
+	}
+	return ossErr(o.be.Delete(ctx, key), errno.FailedUpdate)
+}
+
+func (o objects) DeleteObjects(ctx context.Context, subKeys []string) ([]string, error) {
+	keys := make([]string, 0, len(subKeys))
+	for _, k := range subKeys {
+		key, err := o.base.scopedKey(k)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	deleted, err := o.be.deleteObjects(ctx, keys)
+	for i, k := range deleted {
+		deleted[i] = o.base.relKey(k)
+	}
+	return deleted, ossErr(err, errno.FailedUpdate)
+}
+
+func (o objects) CopyObjectTo(ctx context.Context, destBucket, srcKey, destKey string) error {
+	src, err := o.base.scopedKey(srcKey)
+	if err != nil {
+		return err
+	}
+	dest, err := o.base.scopedKey(destKey)
+	if err != nil {
+		return err
+	}
+	if o.base.sameBucket(destBucket) {
+		destBucket = ""
+	}
+	return ossErr(o.be.copyObject(ctx, destBucket, src, dest), errno.FailedUpdate)
+}
+
+// MoveObjectTo 源与目标相同时不处理，避免复制后删除唯一的对象
+func (o objects) MoveObjectTo(ctx context.Context, destBucket, srcKey, destKey string) error {
+	src, err := o.base.scopedKey(srcKey)
+	if err != nil {
+		return err
+	}
+	dest, err := o.base.scopedKey(destKey)
+	if err != nil {
+		return err
+	}
+	if src == dest && o.base.sameBucket(destBucket) {
+		return nil
+	}
+	if err = o.CopyObjectTo(ctx, destBucket, srcKey, destKey); err != nil {
+		return err
+	}
+	return o.DeleteObject(ctx, srcKey)
+}
+
+func (o objects) ListObjects(ctx context.Context, subPrefix, marker string, maxKeys int) (ListResult, error) {
+	sub, err := NormalizeKey(subPrefix)
+	if err != nil {
+		return ListResult{}, err
+	}
+	prefix := JoinKey(o.base.cfg.UploadDir, sub)
+	if prefix != "" && (sub == "" || strings.HasSuffix(subPrefix, "/")) {
+		prefix += "/"
+	}
+	if marker != "" {
+		marker = JoinKey(o.base.cfg.UploadDir, marker)
+	}
+	res, err := o.be.List(ctx, prefix, marker, maxKeys)
+	if err != nil {
+		return res, ossErr(err, errno.QueryFailed)
+	}
+	for i := range res.Objects {
+		res.Objects[i].Key = o.base.relKey(res.Objects[i].Key)
+	}
+	if res.NextMarker != "" {
+		res.NextMarker = o.base.relKey(res.NextMarker)
+	}
+	return res, nil
+}
+
+func (o objects) StatObject(ctx context.Context, subKey string) (ObjectInfo, error) {
+	key, err := o.base.scopedKey(subKey)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := o.be.Stat(ctx, key)
+	if err != nil {
+		return info, ossErr(err, errno.QueryFailed)
+	}
+	info.Key = subKey
+	return info, nil
+}
+
+func (o objects) Exists(ctx context.Context, subKey string) (bool, error) {
+	key, err := o.base.scopedKey(subKey)
+	if err != nil {
+		return false, err
+	}
+	ok, err := o.be.exists(ctx, key)
+	return ok, ossErr(err, errno.QueryFailed)
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+func (A *AliOss) objects() objects { return objects{base: &A.storeBase, be: A} }
+
+// DeleteObject 删除，不存在时不报错
+func (A *AliOss) DeleteObject(ctx context.Context, subKey string) error {
+	return A.objects().DeleteObject(ctx, subKey)
+}
+
+// DeleteObjects 批量删除，返回已删除的路径
+func (A *AliOss) DeleteObjects(ctx context.Context, subKeys ...string) ([]string, error) {
+	return A.objects().DeleteObjects(ctx, subKeys)
+}
+
+// CopyObject 在当前 bucket 内复制
+func (A *AliOss) CopyObject(ctx context.Context, srcKey, destKey string) error {
+	return A.CopyObjectTo(ctx, A.cfg.Bucket, srcKey, destKey)
+}
+
+// CopyObjectTo 复制到其它 bucket ，目标路径同样在 UploadDir 之下
+func (A *AliOss) CopyObjectTo(ctx context.Context, destBucket, srcKey, destKey string) error {
+	return A.objects().CopyObjectTo(ctx, destBucket, srcKey, destKey)
+}
+
+// MoveObject 在当前 bucket 内改名
+func (A *AliOss) MoveObject(ctx context.Context, srcKey, destKey string) error {
+	return A.MoveObjectTo(ctx, A.cfg.Bucket, srcKey, destKey)
+}
+
+// MoveObjectTo 移动到其它 bucket ：先复制，再删除原对象；源与目标相同时不处理
+func (A *AliOss) MoveObjectTo(ctx context.Context, destBucket, srcKey, destKey string) error {
+	return A.objects().MoveObjectTo(ctx, destBucket, srcKey, destKey)
+}
+
+// ListObjects 按前缀分页列出， marker 为上次返回的 NextMarker
+func (A *AliOss) ListObjects(ctx context.Context, subPrefix, marker string, maxKeys int) (ListResult, error) {
+	return A.objects().ListObjects(ctx, subPrefix, marker, maxKeys)
+}
+
+// StatObject 对象信息，不存在时为 errno.QueryNotFound
+func (A *AliOss) StatObject(ctx context.Context, subKey string) (ObjectInfo, error) {
+	return A.objects().StatObject(ctx, subKey)
+}
+
+// Exists 对象是否存在
+func (A *AliOss) Exists(ctx context.Context, subKey string) (bool, error) {
+	return A.objects().Exists(ctx, subKey)
+}
+
+func (A *AliOss) copyObject(ctx context.Context, destBucket, src, dest string) error {
+	bucket, err := A.bucket()
+	if err != nil {
+		return err
+	}
+	if destBucket == "" {
+		_, err = bucket.CopyObject(src, dest)
+	} else {
+		_, err = bucket.CopyObjectTo(destBucket, dest, src)
+	}
+	return err
+}
+
+func (A *AliOss) deleteObjects(ctx context.Context, keys []string) ([]string, error) {
+	bucket, err := A.bucket()
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for i := 0; i < len(keys); i += maxDeleteBatch {
+		j := i + maxDeleteBatch
+		if j > len(keys) {
+			j = len(keys)
+		}
+		res, err := bucket.DeleteObjects(keys[i:j])
+		deleted = append(deleted, res.DeletedObjects...)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+func (A *AliOss) exists(ctx context.Context, key string) (bool, error) {
+	bucket, err := A.bucket()
+	if err != nil {
+		return false, err
+	}
+	return bucket.IsObjectExist(key)
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+func (L *LocalStore) objects() objects { return objects{base: &L.storeBase, be: L} }
+
+// DeleteObject 删除，不存在时不报错，参考 AliOss.DeleteObject
+func (L *LocalStore) DeleteObject(ctx context.Context, subKey string) error {
+	return L.objects().DeleteObject(ctx, subKey)
+}
+
+// DeleteObjects 批量删除，返回已删除的路径
+func (L *LocalStore) DeleteObjects(ctx context.Context, subKeys ...string) ([]string, error) {
+	return L.objects().DeleteObjects(ctx, subKeys)
+}
+
+// CopyObject 复制
+func (L *LocalStore) CopyObject(ctx context.Context, srcKey, destKey string) error {
+	return L.objects().CopyObjectTo(ctx, "", srcKey, destKey)
+}
+
+// MoveObject 改名；源与目标相同时不处理
+func (L *LocalStore) MoveObject(ctx context.Context, srcKey, destKey string) error {
+	return L.objects().MoveObjectTo(ctx, "", srcKey, destKey)
+}
+
+// ListObjects 按前缀分页列出， marker 为上次返回的 NextMarker
+func (L *LocalStore) ListObjects(ctx context.Context, subPrefix, marker string, maxKeys int) (ListResult, error) {
+	return L.objects().ListObjects(ctx, subPrefix, marker, maxKeys)
+}
+
+// StatObject 对象信息，不存在时为 errno.QueryNotFound
+func (L *LocalStore) StatObject(ctx context.Context, subKey string) (ObjectInfo, error) {
+	return L.objects().StatObject(ctx, subKey)
+}
+
+// Exists 对象是否存在
+func (L *LocalStore) Exists(ctx context.Context, subKey string) (bool, error) {
+	return L.objects().Exists(ctx, subKey)
+}
+
+func (L *LocalStore) copyObject(ctx context.Context, destBucket, src, dest string) error {
+	if destBucket != "" {
+		return errno.BadRequest.SetMsg("本地存储不支持其它 bucket: " + destBucket)
+	}
+	r, err := L.Download(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return L.Upload(ctx, dest, r)
+}
+
+func (L *LocalStore) deleteObjects(ctx context.Context, keys []string) ([]string, error) {
+	var deleted []string
+	for _, k := range keys {
+		if err := L.Delete(ctx, k); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, k)
+	}
+	return deleted, nil
+}
+
+func (L *LocalStore) exists(ctx context.Context, key string) (bool, error) {
+	if _, err := L.Stat(ctx, key); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package alioss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xtulnx/go-srv/errno"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestObjects(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(AliOssConfig{UploadDir: "demo/", LocalDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"demo/a/1.txt", "demo/a/2.txt", "demo/b.txt", "demob/x.txt"} {
+		if err = store.Upload(ctx, k, strings.NewReader(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(key string) string {
+		r, err := store.Download(ctx, key)
+		if err != nil {
+			return ""
+		}
+		defer r.Close()
+		b, _ := io.ReadAll(r)
+		return string(b)
+	}
+	keys := func(res ListResult) string {
+		var list []string
+		for _, o := range res.Objects {
+			list = append(list, o.Key)
+		}
+		return fmt.Sprint(list)
+	}
+
+	// 列出，路径相对 UploadDir ，不包括 demob
+	res, err := store.ListObjects(ctx, "", "", 2)
+	if err != nil || keys(res) != "[a/1.txt a/2.txt]" || res.NextMarker != "a/2.txt" {
+		t.Fatalf("list: %v %+v %v", keys(res), res, err)
+	}
+	if res, err = store.ListObjects(ctx, "", res.NextMarker, 2); err != nil || keys(res) != "[b.txt]" {
+		t.Fatalf("list: %v %v", keys(res), err)
+	}
+	if res, err = store.ListObjects(ctx, "a/", "", 0); err != nil || keys(res) != "[a/1.txt a/2.txt]" {
+		t.Fatalf("list prefix: %v %v", keys(res), err)
+	}
+
+	// 复制、移动
+	if err = store.CopyObject(ctx, "b.txt", "c/b.txt"); err != nil || read("demo/c/b.txt") != "demo/b.txt" {
+		t.Fatalf("copy: %v", err)
+	}
+	if err = store.MoveObject(ctx, "c/b.txt", "d.txt"); err != nil || read("demo/d.txt") != "demo/b.txt" {
+		t.Fatalf("move: %v", err)
+	}
+	if ok, _ := store.Exists(ctx, "c/b.txt"); ok {
+		t.Fatal("move: source exists")
+	}
+	// 源与目标相同时不能删除对象
+	for _, dest := range []string{"d.txt", "./d.txt", "/d.txt"} {
+		if err = store.MoveObject(ctx, "d.txt", dest); err != nil || read("demo/d.txt") != "demo/b.txt" {
+			t.Fatalf("move same key %s: %v", dest, err)
+		}
+	}
+	if err = store.objects().MoveObjectTo(ctx, "other", "d.txt", "e.txt"); !errors.Is(err, errno.BadRequest) {
+		t.Fatalf("other bucket: %v", err)
+	}
+
+	// 删除只在 UploadDir 之下
+	if err = store.DeleteObject(ctx, "../demob/x.txt"); !errors.Is(err, errno.BadRequest) || read("demob/x.txt") == "" {
+		t.Fatalf("delete outside: %v", err)
+	}
+	if err = store.DeleteObject(ctx, "d.txt"); err != nil || read("demo/d.txt") != "" {
+		t.Fatalf("delete: %v", err)
+	}
+	deleted, err := store.DeleteObjects(ctx, "a/1.txt", "a/2.txt")
+	if err != nil || fmt.Sprint(deleted) != "[a/1.txt a/2.txt]" {
+		t.Fatalf("delete objects: %v %v", deleted, err)
+	}
+	if _, err = store.StatObject(ctx, "a/1.txt"); !errors.Is(err, errno.QueryNotFound) {
+		t.Fatalf("stat: %v", err)
+	}
+	if info, err := store.StatObject(ctx, "b.txt"); err != nil || info.Key != "b.txt" || info.Size != int64(len("demo/b.txt")) {
+		t.Fatalf("stat: %+v %v", info, err)
+	}
+
+	// 相对路径按「/」分隔
+	for key, want := range map[string]string{"demo/a/1.txt": "a/1.txt", "demo": "demo", "demob/x.txt": "demob/x.txt"} {
+		if got := store.relKey(key); got != want {
+			t.Errorf("relKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
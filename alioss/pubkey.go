@@ -0,0 +1,140 @@
+package alioss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 回调签名的公钥
+//
+//	x-oss-pub-key-url 来自请求头，只允许 OSS 公钥的域名，避免被指向伪造的公钥；
+//	OSS 给出的地址为 http （如 http://gosspublic.alicdn.com/callback_pub_key_v1.pem ），获取时统一使用 https 。
+//	不支持域名后缀：bucket 的域名（如 xxx.oss-cn-hangzhou.aliyuncs.com ）可以由任何人上传内容
+//	公钥按域名与路径缓存 PubKeyTTL ，忽略地址中的参数
+
+// DefaultPubKeyHosts 允许获取公钥的域名，完整匹配
+var DefaultPubKeyHosts = []string{"gosspublic.alicdn.com"}
+
+// DefaultPubKeyTTL 公钥默认的缓存时间
+const DefaultPubKeyTTL = time.Hour
+
+// maxPubKeys 最多缓存的公钥数量
+const maxPubKeys = 16
+
+type pubKeyItem struct {
+	key     []byte
+	expires time.Time
+}
+
+// pubKeyCache 公钥的获取与缓存
+type pubKeyCache struct {
+	client *http.Client
+	hosts  []string
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	items map[string]pubKeyItem
+}
+
+func newPubKeyCache(cfg AliOssConfig) *pubKeyCache {
+	c := &pubKeyCache{
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 3 * time.Second}).DialContext,
+				TLSHandshakeTimeout:   3 * time.Second,
+				ResponseHeaderTimeout: 3 * time.Second,
+			},
+			// 不跟随跳转，避免绕过域名限制
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		hosts: append(append([]string{}, DefaultPubKeyHosts...), cfg.PubKeyHosts...),
+		ttl:   DefaultPubKeyTTL,
+		items: map[string]pubKeyItem{},
+	}
+	if cfg.PubKeyTTL > 0 {
+		c.ttl = time.Duration(cfg.PubKeyTTL) * time.Second
+	}
+	return c
+}
+
+// allowed 地址是否为 http(s) 且在允许的域名中
+func (c *pubKeyCache) allowed(u *url.URL) bool {
+	if (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	for _, h := range c.hosts {
+		h = strings.ToLower(h)
+		if host == h || (strings.ToLower(u.Hostname()) == h && u.Port() == "") {
+			return true
+		}
+	}
+	return false
+}
+
+// get 获取公钥，优先使用缓存
+func (c *pubKeyCache) get(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !c.allowed(u) {
+		return nil, fmt.Errorf("不允许的公钥地址: [%s]", rawURL)
+	}
+	// 只使用域名与路径，参数不同的地址视为同一个
+	keyURL := "https://" + strings.ToLower(u.Host) + path.Clean("/"+u.EscapedPath())
+	c.mu.Lock()
+	item, ok := c.items[keyURL]
+	c.mu.Unlock()
+	if ok && time.Now().Before(item.expires) {
+		return item.key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, keyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取公钥失败: %d", resp.StatusCode)
+	}
+	key, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	for k, v := range c.items {
+		if now.After(v.expires) {
+			delete(c.items, k)
+		}
+	}
+	if len(c.items) >= maxPubKeys {
+		c.items = map[string]pubKeyItem{}
+	}
+	c.items[keyURL] = pubKeyItem{key: key, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return key, nil
+}
+
+// SetHTTPClient 替换获取回调公钥的 http 客户端
+func (A *AliOss) SetHTTPClient(client *http.Client) {
+	A.pubKeys.client = client
+}
@@ -0,0 +1,261 @@
+package alioss
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3Store S3 兼容的存储，如 MinIO 、 AWS S3
+//
+//	表单直传使用 S3 的 PostPolicy ，客户端需要把 PolicyToken.FormData 一并提交；不支持上传回调
+type S3Store struct {
+	storeBase
+
+	client *minio.Client
+}
+
+// NewS3Store 创建 S3 存储， EndPoint 不带协议时默认使用 https
+func NewS3Store(cfg AliOssConfig) (*S3Store, error) {
+	if cfg.AccessKeyId == "" || cfg.AccessKeySecret == "" {
+		return nil, fmt.Errorf("无效 s3 授权")
+	}
+	endpoint, secure := cfg.EndPoint, true
+	if u, err := url.Parse(cfg.EndPoint); err == nil && u.Host != "" {
+		endpoint, secure = u.Host, u.Scheme != "http"
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyId, cfg.AccessKeySecret, ""),
+		Secure: secure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{storeBase: storeBase{cfg: cfg}, client: client}, nil
+}
+
+// GetClient minio 客户端
+func (S *S3Store) GetClient() *minio.Client {
+	return S.client
+}
+
+// UploadFile 上传本地文件
+func (S *S3Store) UploadFile(localFile string, objectName string, opts ...UploadOption) error {
+	return S.UploadLarge(context.Background(), objectName, localFile, opts...)
+}
+
+// Upload 上传数据，长度未知时按分片上传
+func (S *S3Store) Upload(ctx context.Context, objectName string, r io.Reader, opts ...UploadOption) error {
+	o := newUploadOptions(objectName, opts)
+	size := o.Size
+	if size <= 0 {
+		size = -1
+	}
+	_, err := S.client.PutObject(ctx, S.cfg.Bucket, objectName, r, size, o.s3Options())
+	return err
+}
+
+// UploadLarge 分片并发上传。不支持断点续传
+func (S *S3Store) UploadLarge(ctx context.Context, objectName string, localFile string, opts ...UploadOption) error {
+	o := newUploadOptions(objectName, opts)
+	if o.Size <= 0 {
+		if st, err := os.Stat(localFile); err == nil {
+			o.Size = st.Size()
+		}
+	}
+	_, err := S.client.FPutObject(ctx, S.cfg.Bucket, objectName, localFile, o.s3Options())
+	return err
+}
+
+// Download 下载，需要关闭
+func (S *S3Store) Download(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	obj, err := S.client.GetObject(ctx, S.cfg.Bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject 不会请求，先取一次信息以便尽早返回错误
+	if _, err = obj.Stat(); err != nil {
+		_ = obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Delete 删除
+func (S *S3Store) Delete(ctx context.Context, objectName string) error {
+	return S.client.RemoveObject(ctx, S.cfg.Bucket, objectName, minio.RemoveObjectOptions{})
+}
+
+// List 按前缀列出对象
+func (S *S3Store) List(ctx context.Context, prefix, marker string, maxKeys int) (ListResult, error) {
+	var res ListResult
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for o := range S.client.ListObjects(ctx, S.cfg.Bucket, minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: marker,
+		Recursive:  true,
+		MaxKeys:    maxKeys,
+	}) {
+		if o.Err != nil {
+			return res, o.Err
+		}
+		if len(res.Objects) >= maxKeys {
+			res.IsTruncated = true
+			break
+		}
+		res.Objects = append(res.Objects, s3ObjectInfo(o))
+	}
+	if res.IsTruncated {
+		res.NextMarker = res.Objects[len(res.Objects)-1].Key
+	}
+	return res, nil
+}
+
+// Stat 对象信息
+func (S *S3Store) Stat(ctx context.Context, objectName string) (ObjectInfo, error) {
+	o, err := S.client.StatObject(ctx, S.cfg.Bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{Key: objectName}, err
+	}
+	return s3ObjectInfo(o), nil
+}
+
+func s3ObjectInfo(o minio.ObjectInfo) ObjectInfo {
+	info := ObjectInfo{
+		Key:          o.Key,
+		Size:         o.Size,
+		ETag:         strings.Trim(o.ETag, `"`),
+		ContentType:  o.ContentType,
+		LastModified: o.LastModified,
+	}
+	for k, v := range o.UserMetadata {
+		if info.Meta == nil {
+			info.Meta = map[string]string{}
+		}
+		info.Meta[strings.ToLower(k)] = v
+	}
+	return info
+}
+
+// Presign 生成带签名的临时地址
+func (S *S3Store) Presign(ctx context.Context, method string, objectName string, expire time.Duration) (string, error) {
+	var u *url.URL
+	var err error
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		u, err = S.client.PresignedGetObject(ctx, S.cfg.Bucket, objectName, expire, nil)
+	case http.MethodPut:
+		u, err = S.client.PresignedPutObject(ctx, S.cfg.Bucket, objectName, expire)
+	default:
+		return "", fmt.Errorf("不支持的方法: [%s]", method)
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// GetPolicyToken 获取 上传授权 token ，使用 S3 的 PostPolicy
+//
+//	Host 为上传地址； FormData 为需要额外提交的 x-amz-* 等字段
+//
+// Deprecated: 失败时只记录日志并返回空的 token ，使用 GetPolicyTokenWith
+func (S *S3Store) GetPolicyToken(prefix, callbackBody string) PolicyToken {
+	token, err := S.GetPolicyTokenWith(prefix, PolicyOptions{CallbackBody: callbackBody})
+	return policyTokenOrLog(token, err, prefix)
+}
+
+// GetPolicyTokenWith 按选项获取 上传授权 token
+//
+//	S3 不支持 in 条件，多个 ContentTypes 时返回错误，需要时改用 ContentTypePrefix ； Fields 仅支持
+//	success_action_status 、 success_action_redirect ； CallbackBody 忽略
+func (S *S3Store) GetPolicyTokenWith(prefix string, opts PolicyOptions) (PolicyToken, error) {
+	if opts.Expire <= 0 {
+		opts.Expire = DefaultPolicyExpire
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultPolicyMaxSize
+	}
+	if len(opts.Conditions) > 0 {
+		return PolicyToken{}, fmt.Errorf("s3 不支持自定义条件")
+	}
+	uploadDir, err := joinSubKey(S.cfg.UploadDir, prefix)
+	if err != nil {
+		return PolicyToken{}, err
+	}
+	expire := time.Now().Add(opts.Expire)
+	token := PolicyToken{
+		AccessKeyId: S.cfg.AccessKeyId,
+		Expire:      expire.Unix(),
+		Directory:   uploadDir,
+	}
+
+	p := minio.NewPostPolicy()
+	_ = p.SetBucket(S.cfg.Bucket)
+	_ = p.SetExpires(expire)
+	if err := p.SetContentLengthRange(opts.MinSize, opts.MaxSize); err != nil {
+		return token, err
+	}
+	if opts.Key != "" {
+		if token.FileKey, err = joinSubKey(uploadDir, opts.Key); err != nil {
+			return PolicyToken{}, err
+		}
+		_ = p.SetKey(token.FileKey)
+	} else {
+		_ = p.SetKeyStartsWith(uploadDir)
+	}
+	switch {
+	case len(opts.ContentTypes) == 1:
+		_ = p.SetContentType(opts.ContentTypes[0])
+	case len(opts.ContentTypes) > 1:
+		// 不能放宽为前缀，否则会允许列表外的类型（如 image/svg+xml ）
+		return token, fmt.Errorf("s3 不支持多个 Content-Type ，请使用 ContentTypePrefix")
+	}
+	if opts.ContentTypePrefix != "" {
+		_ = p.SetContentTypeStartsWith(opts.ContentTypePrefix)
+	}
+	for k, v := range opts.Meta {
+		if err := p.SetUserMetadata(k, v); err != nil {
+			return token, err
+		}
+	}
+	for k, v := range opts.Fields {
+		var err error
+		switch strings.ToLower(k) {
+		case "success_action_status":
+			err = p.SetSuccessStatusAction(v)
+		case "success_action_redirect":
+			err = p.SetSuccessActionRedirect(v)
+		default:
+			err = fmt.Errorf("s3 不支持的表单字段: [%s]", k)
+		}
+		if err != nil {
+			return token, err
+		}
+	}
+
+	u, formData, err := S.client.PresignedPostPolicy(context.Background(), p)
+	if err != nil {
+		return token, err
+	}
+	token.Host = u.String()
+	token.Policy = formData["policy"]
+	token.Signature = formData["x-amz-signature"]
+	delete(formData, "policy")
+	delete(formData, "x-amz-signature")
+	delete(formData, "key")
+	token.FormData = formData
+	return token, nil
+}
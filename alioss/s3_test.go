@@ -0,0 +1,28 @@
+package alioss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestS3Policy(t *testing.T) {
+	store, err := NewS3Store(AliOssConfig{
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+		EndPoint:        "http://127.0.0.1:9000",
+		Region:          "us-east-1",
+		Bucket:          "demo",
+		UploadDir:       "j00",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.GetPolicyTokenWith("a/", PolicyOptions{ContentTypePrefix: "image/"})
+	if err != nil || token.Policy == "" || !strings.HasPrefix(token.Directory, "j00/a") {
+		t.Fatalf("policy: %+v %v", token, err)
+	}
+	// 多个类型不能放宽为前缀
+	if _, err = store.GetPolicyTokenWith("a/", PolicyOptions{ContentTypes: []string{"image/png", "image/jpeg"}}); err == nil {
+		t.Fatal("expect error for multiple content types")
+	}
+}
@@ -1,6 +1,7 @@
 package alioss
 
 import (
+	"context"
 	"crypto"
 	"crypto/hmac"
 	"crypto/md5"
@@ -11,14 +12,17 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/xtulnx/go-srv/errno"
+	"github.com/xtulnx/go-srv/logkit"
 	"github.com/xtulnx/go-srv/utils"
 	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -52,6 +56,10 @@ type PolicyToken struct {
 
 	Directory string `json:"dir,omitempty"` // 限制上传的文件前缀。直接拼接，**不要再加「/」**
 	FileKey   string `json:"key,omitempty"` // 指定文件路径。如果有值，则相当于本次token只能用于上传一个文件
+
+	FormData map[string]string `json:"form,omitempty"` // 需要额外提交的表单字段，如 s3 的 x-amz-*
+
+	SecurityToken string `json:"security_token,omitempty"` // STS 临时授权，已包含在 FormData 的 x-oss-security-token
 }
 
 type ConfigStruct struct {
@@ -69,32 +77,159 @@ type CallbackParam struct {
 	CallbackBodyType string `json:"callbackBodyType"`
 }
 
+// PolicyOptions 表单直传的限制，用于不同的上传场景（头像、文档、视频等）
+//
+//	token, err := cli.GetPolicyTokenWith("avatar/", alioss.PolicyOptions{
+//	    Expire:            time.Minute,
+//	    MaxSize:           2 << 20,
+//	    ContentTypePrefix: "image/",
+//	    Key:               fmt.Sprintf("%d.png", uid),
+//	})
+type PolicyOptions struct {
+	Expire  time.Duration // 有效期，默认 DefaultPolicyExpire
+	MinSize int64         // 文件大小下限（字节）
+	MaxSize int64         // 文件大小上限（字节），默认 DefaultPolicyMaxSize
+
+	ContentTypes      []string // 允许的 Content-Type ，客户端需提交同名表单字段； s3 只支持一个
+	ContentTypePrefix string   // Content-Type 的前缀，如 image/
+
+	// 指定文件路径（在 prefix 之下），此时 token 只能用于上传该文件，并填充 FileKey
+	Key string
+
+	Meta       map[string]string // 自定义元信息，放到 FormData ，需一并提交
+	Fields     map[string]string // 额外的表单字段，要求值相等，放到 FormData
+	Conditions [][]interface{}   // 额外的原始条件。 s3 不支持
+
+	CallbackBody string // 追加的回调参数
+}
+
+const (
+	DefaultPolicyExpire  = 30 * time.Second // 表单直传默认有效期
+	DefaultPolicyMaxSize = 30 * 1024 * 1024 // 表单直传默认大小上限
+)
+
 // GetPolicyToken 获取 上传授权 token
 //
 //	prefix 路径前缀，在整体的配置 UploadDir 之下，如果是 以 「/」结尾，则表示"目录"
+//
+// Deprecated: 失败时（如获取 STS 临时授权失败）只记录日志并返回空的 token ，使用 GetPolicyTokenWith
 func (A *AliOss) GetPolicyToken(prefix, callbackBody string) PolicyToken {
-	uploadDir := filepath.Join(A.cfg.UploadDir, prefix)
-	callbackUrl := A.cfg.Callback
-	expire_end := time.Now().Unix() + 30
+	token, err := A.GetPolicyTokenWith(prefix, PolicyOptions{CallbackBody: callbackBody})
+	return policyTokenOrLog(token, err, prefix)
+}
+
+// policyTokenOrLog 兼容旧的 GetPolicyToken ：记录错误并返回空的 token
+func policyTokenOrLog(token PolicyToken, err error, prefix string) PolicyToken {
+	if err != nil {
+		logkit.ForTask("alioss").WithError(err).WithField("prefix", prefix).Error("获取上传授权失败")
+		return PolicyToken{}
+	}
+	return token
+}
+
+// GetPolicyTokenWith 按选项获取 上传授权 token
+//
+//	设置了 CredentialProvider 时使用限定在上传目录的临时授权签名，有效期不超过临时授权的 Expiration
+func (A *AliOss) GetPolicyTokenWith(prefix string, opts PolicyOptions) (PolicyToken, error) {
+	if A.creds == nil {
+		return newPolicyToken(A.cfg, prefix, opts)
+	}
+	cfg := A.cfg
+	dir, err := joinSubKey(cfg.UploadDir, prefix)
+	if err != nil {
+		return PolicyToken{}, err
+	}
+	c, err := A.creds.Credentials(context.Background(), dir)
+	if err != nil {
+		return PolicyToken{}, err
+	}
+	// 不超过临时授权的有效期；已过期（或不足 1 秒）时报错
+	remaining := time.Until(c.Expiration)
+	if remaining < time.Second {
+		return PolicyToken{}, fmt.Errorf("临时授权已过期: %s", c.Expiration.Format(time.RFC3339))
+	}
+	cfg.AccessKeyId, cfg.AccessKeySecret = c.AccessKeyId, c.AccessKeySecret
+	fields := map[string]string{strings.ToLower(oss.HTTPHeaderOssSecurityToken): c.SecurityToken}
+	for k, v := range opts.Fields {
+		fields[k] = v
+	}
+	opts.Fields = fields
+	if opts.Expire <= 0 {
+		opts.Expire = DefaultPolicyExpire
+	}
+	if remaining < opts.Expire {
+		opts.Expire = remaining
+	}
+	token, err := newPolicyToken(cfg, prefix, opts)
+	token.SecurityToken = c.SecurityToken
+	return token, err
+}
+
+// newPolicyToken 按 OSS 的表单上传格式签名， LocalStore 也使用相同的格式
+func newPolicyToken(cfg AliOssConfig, prefix string, opts PolicyOptions) (PolicyToken, error) {
+	if opts.Expire <= 0 {
+		opts.Expire = DefaultPolicyExpire
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultPolicyMaxSize
+	}
+	if opts.MinSize > opts.MaxSize {
+		return PolicyToken{}, fmt.Errorf("无效的文件大小限制: %d-%d", opts.MinSize, opts.MaxSize)
+	}
+	uploadDir, err := joinSubKey(cfg.UploadDir, prefix)
+	if err != nil {
+		return PolicyToken{}, err
+	}
+	callbackUrl := cfg.Callback
+	expire_end := time.Now().Add(opts.Expire).Unix()
 
 	//create post policy json
 	config := ConfigStruct{
 		Expiration: time.Unix(expire_end, 0).UTC().Format("2006-01-02T15:04:05Z"),
 		Conditions: [][]interface{}{
-			// 指定前缀
-			{"starts-with", "$key", uploadDir},
-			// 指定路径的方式
-			//{"eq", "$key", ""},
 			// 限制上传文件大小。
-			{"content-length-range", 0, 30 * 1024 * 1024},
+			{"content-length-range", opts.MinSize, opts.MaxSize},
 		},
 	}
+	fileKey := ""
+	if opts.Key != "" {
+		// 指定路径的方式
+		if fileKey, err = joinSubKey(uploadDir, opts.Key); err != nil {
+			return PolicyToken{}, err
+		}
+		config.Conditions = append(config.Conditions, []interface{}{"eq", "$key", fileKey})
+	} else {
+		// 指定前缀
+		config.Conditions = append(config.Conditions, []interface{}{"starts-with", "$key", uploadDir})
+	}
+	if len(opts.ContentTypes) > 0 {
+		config.Conditions = append(config.Conditions, []interface{}{"in", "$content-type", opts.ContentTypes})
+	}
+	if opts.ContentTypePrefix != "" {
+		config.Conditions = append(config.Conditions, []interface{}{"starts-with", "$content-type", opts.ContentTypePrefix})
+	}
+	formData := map[string]string{}
+	for k, v := range opts.Meta {
+		formData[strings.ToLower(oss.HTTPHeaderOssMetaPrefix)+k] = v
+	}
+	for k, v := range opts.Fields {
+		formData[k] = v
+	}
+	names := make([]string, 0, len(formData))
+	for k := range formData {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		config.Conditions = append(config.Conditions, []interface{}{"eq", "$" + k, formData[k]})
+	}
+	config.Conditions = append(config.Conditions, opts.Conditions...)
 
 	//calucate signature
 	result, _ := json.Marshal(config)
 	debyte := base64.StdEncoding.EncodeToString(result)
 
-	h := hmac.New(func() hash.Hash { return sha1.New() }, []byte(A.cfg.AccessKeySecret))
+	h := hmac.New(func() hash.Hash { return sha1.New() }, []byte(cfg.AccessKeySecret))
 	io.WriteString(h, debyte)
 	signedStr := base64.StdEncoding.EncodeToString(h.Sum(nil))
 
@@ -113,45 +248,47 @@ func (A *AliOss) GetPolicyToken(prefix, callbackBody string) PolicyToken {
 		"format=${imageInfo.format}",
 	}, "&")
 	callbackParam.CallbackBodyType = "application/x-www-form-urlencoded"
-	if callbackBody != "" {
-		callbackParam.CallbackBody += "&" + callbackBody
+	if opts.CallbackBody != "" {
+		callbackParam.CallbackBody += "&" + opts.CallbackBody
 	}
 	callback_str, _ := json.Marshal(callbackParam)
 	callbackBase64 := base64.StdEncoding.EncodeToString(callback_str)
 
 	policyToken := PolicyToken{
-		AccessKeyId: A.cfg.AccessKeyId,
-		Host:        A.cfg.Host,
+		AccessKeyId: cfg.AccessKeyId,
+		Host:        cfg.Host,
 		Expire:      expire_end,
 		Signature:   signedStr,
 		Directory:   uploadDir,
+		FileKey:     fileKey,
 		Policy:      debyte,
 		Callback:    callbackBase64,
 	}
-	return policyToken
+	if len(formData) > 0 {
+		policyToken.FormData = formData
+	}
+	return policyToken, nil
 }
 
 // -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
 
 // getPublicKey : Get PublicKey bytes from Request.URL
-func getPublicKey(logger utils.Logger, r *http.Request) ([]byte, error) {
-	var bytePublicKey []byte
-	if publicKeyURLBase64 := r.Header.Get("x-oss-pub-key-url"); publicKeyURLBase64 == "" {
+//
+//	只允许 OSS 的域名，并使用缓存，参考 pubKeyCache
+func (A *AliOss) getPublicKey(logger utils.Logger, r *http.Request) ([]byte, error) {
+	publicKeyURLBase64 := r.Header.Get("x-oss-pub-key-url")
+	if publicKeyURLBase64 == "" {
 		logger.Warn("GetPublicKey from Request header failed :  No x-oss-pub-key-url field. ")
-		return bytePublicKey, errors.New("no x-oss-pub-key-url field in Request header ")
-	} else if publicKeyURL, e1 := base64.StdEncoding.DecodeString(publicKeyURLBase64); e1 != nil {
-		return nil, e1
-		// TODO: 考虑使用缓存
-	} else if responsePublicKeyURL, e2 := http.Get(string(publicKeyURL)); e2 != nil {
-		logger.Warnf("Get PublicKey Content from URL failed : %s \n", e2.Error())
-		return bytePublicKey, e2
-	} else {
-		bytePublicKey, e2 = ioutil.ReadAll(responsePublicKeyURL.Body)
-		_ = responsePublicKeyURL.Body.Close()
-		if e2 != nil {
-			logger.Warnf("Read PublicKey Content from URL failed : %s \n", e2.Error())
-			return bytePublicKey, e2
-		}
+		return nil, errors.New("no x-oss-pub-key-url field in Request header ")
+	}
+	publicKeyURL, err := base64.StdEncoding.DecodeString(publicKeyURLBase64)
+	if err != nil {
+		return nil, err
+	}
+	bytePublicKey, err := A.pubKeys.get(r.Context(), string(publicKeyURL))
+	if err != nil {
+		logger.Warnf("Get PublicKey Content from URL failed : %s \n", err.Error())
+		return nil, err
 	}
 	return bytePublicKey, nil
 }
@@ -262,7 +399,7 @@ func verifySignature(logger utils.Logger, bytePublicKey []byte, byteMd5 []byte,
 
 // VerifyCallback 回调校验
 func (A *AliOss) VerifyCallback(logger utils.Logger, r *http.Request) (url.Values, error) {
-	bytePublicKey, err := getPublicKey(logger, r)
+	bytePublicKey, err := A.getPublicKey(logger, r)
 	if err != nil {
 		return nil, err
 	}
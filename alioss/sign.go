@@ -0,0 +1,127 @@
+package alioss
+
+import (
+	"fmt"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/xtulnx/go-srv/logkit"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 私有 bucket 的访问地址
+//
+//	配置 Private 后 GenUrl 、 GenUrlThumbnail 返回带签名的临时地址
+//
+//	   u, err := cli.SignUrl(key,
+//	       alioss.WithExpire(10*time.Minute),
+//	       alioss.WithProcess("image/resize,w_200"),
+//	       alioss.WithDownloadName("报表.xlsx"),
+//	       alioss.WithHost("https://cdn.example.com"), // 需在 Host 或 HostAlias 中
+//	   )
+//
+//	签名只与 bucket 和对象路径有关，因此可以替换为绑定到 bucket 的自定义域名或 CDN 域名（CDN 需回源私有 bucket 并保留参数）
+
+// DefaultSignExpire 签名地址默认的有效期
+const DefaultSignExpire = time.Hour
+
+// URLOptions 签名地址的选项
+type URLOptions struct {
+	Expire      time.Duration // 有效期，默认 SignExpire 或 DefaultSignExpire
+	Process     string        // 图片处理参数，即 x-oss-process ，如 image/resize,w_200
+	Disposition string        // 覆盖下载时的 Content-Disposition
+	Host        string        // 使用的域名，需在 Host 或 HostAlias 中；默认 Host
+}
+
+// URLOption 设置签名地址的选项
+type URLOption func(o *URLOptions)
+
+func WithExpire(expire time.Duration) URLOption {
+	return func(o *URLOptions) { o.Expire = expire }
+}
+
+func WithProcess(process string) URLOption {
+	return func(o *URLOptions) { o.Process = process }
+}
+
+func WithDisposition(disposition string) URLOption {
+	return func(o *URLOptions) { o.Disposition = disposition }
+}
+
+// WithDownloadName 以附件方式下载，并指定文件名
+func WithDownloadName(name string) URLOption {
+	return WithDisposition(fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`,
+		strings.ReplaceAll(name, `"`, ""), url.PathEscape(name)))
+}
+
+func WithHost(host string) URLOption {
+	return func(o *URLOptions) { o.Host = host }
+}
+
+// SignUrl 生成带签名的 GET 地址
+//
+//	key 可以是对象路径，或 Host 、 HostAlias 下的完整地址（此时默认使用原来的域名）；其它地址原样返回
+func (A *AliOss) SignUrl(key string, opts ...URLOption) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	o := URLOptions{Expire: DefaultSignExpire, Host: A.cfg.Host}
+	if A.cfg.SignExpire > 0 {
+		o.Expire = time.Duration(A.cfg.SignExpire) * time.Second
+	}
+	if isURL(key) {
+		host, k, ok := A.matchHost(key)
+		if !ok {
+			return key, nil
+		}
+		o.Host, key = host, k
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	host, ok := A.normalizeHost(o.Host)
+	if !ok {
+		return "", fmt.Errorf("域名未配置: [%s]", o.Host)
+	}
+
+	var ossOpts []oss.Option
+	if o.Process != "" {
+		ossOpts = append(ossOpts, oss.Process(o.Process))
+	}
+	if o.Disposition != "" {
+		ossOpts = append(ossOpts, oss.ResponseContentDisposition(o.Disposition))
+	}
+	bucket, err := A.bucket()
+	if err != nil {
+		return "", err
+	}
+	signed, err := bucket.SignURL(strings.TrimPrefix(key, "/"), oss.HTTPGet, int64(o.Expire/time.Second), ossOpts...)
+	if err != nil {
+		return "", err
+	}
+	// EndPoint 可能是内网域名，替换为对外的域名
+	u, err := url.Parse(signed)
+	if err != nil {
+		return "", err
+	}
+	return PathJoin(host, u.RequestURI()), nil
+}
+
+// GenUrl 完整的对外可访问的URL。配置 Private 时为带签名的临时地址，签名失败时记录日志并返回不带签名的地址；
+// 需要处理错误时使用 SignUrl
+func (A *AliOss) GenUrl(key string) string {
+	if !A.cfg.Private {
+		return A.storeBase.GenUrl(key)
+	}
+	u, err := A.SignUrl(key)
+	if err != nil {
+		logSignErr(key, err)
+		return A.storeBase.GenUrl(key)
+	}
+	return u
+}
+
+// logSignErr 记录签名失败，此时返回的地址无法访问私有 bucket
+func logSignErr(key string, err error) {
+	logkit.ForTask("alioss").WithError(err).WithField("key", key).Error("生成签名地址失败")
+}
@@ -0,0 +1,120 @@
+package alioss
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newSignClient(t *testing.T, private bool, host string) *AliOss {
+	cli, err := NewClient(AliOssConfig{
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+		EndPoint:        "oss-cn-shenzhen-internal.aliyuncs.com",
+		Bucket:          "j00",
+		Host:            host,
+		HostAlias:       []string{"cdn.example.com"},
+		Private:         private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+// checkSigned 检查地址的域名、路径，以及是否带签名
+func checkSigned(t *testing.T, name, u, prefix string, signed bool) url.Values {
+	t.Helper()
+	if !strings.HasPrefix(u, prefix) {
+		t.Fatalf("%s: %s, want prefix %s", name, u, prefix)
+	}
+	p, err := url.Parse(u)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	q := p.Query()
+	if has := q.Get("Signature") != "" && q.Get("Expires") != ""; has != signed {
+		t.Fatalf("%s: %s, signed = %v", name, u, has)
+	}
+	return q
+}
+
+func TestSignUrl(t *testing.T) {
+	const host = "https://j00.oss-cn-shenzhen.aliyuncs.com"
+	cli := newSignClient(t, true, host)
+
+	u, err := cli.SignUrl("b.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSigned(t, "default host", u, host+"/b.png?", true)
+
+	// 替换为 HostAlias
+	u, err = cli.SignUrl("b.png", WithHost("https://cdn.example.com"), WithProcess("image/resize,w_200"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := checkSigned(t, "alias", u, "https://cdn.example.com/b.png?", true); q.Get("x-oss-process") != "image/resize,w_200" {
+		t.Fatalf("alias process: %s", u)
+	}
+	u, err = cli.SignUrl("b.png", WithHost("cdn.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSigned(t, "alias without scheme", u, "https://cdn.example.com/b.png?", true)
+
+	// 完整地址保留原来的域名
+	u, err = cli.SignUrl("https://cdn.example.com/b.png?x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSigned(t, "alias url", u, "https://cdn.example.com/b.png?", true)
+
+	// 其它地址原样返回
+	if u, err = cli.SignUrl("https://other.example.com/a.png"); err != nil || u != "https://other.example.com/a.png" {
+		t.Fatalf("other url: %s %v", u, err)
+	}
+	// 未配置的域名
+	if _, err = cli.SignUrl("a.png", WithHost("https://evil.example.com")); err == nil {
+		t.Fatal("expect host error")
+	}
+}
+
+func TestGenUrlPrivate(t *testing.T) {
+	const host = "https://j00.oss-cn-shenzhen.aliyuncs.com"
+	const process = "image/resize,w_200"
+
+	pub := newSignClient(t, false, host)
+	if u := pub.GenUrl("a.png"); u != host+"/a.png" {
+		t.Fatalf("public: %s", u)
+	}
+	if u := pub.GenUrlProcess("a.png", process); u != host+"/a.png?x-oss-process="+process {
+		t.Fatalf("public process: %s", u)
+	}
+
+	pri := newSignClient(t, true, host)
+	checkSigned(t, "private", pri.GenUrl("a.png"), host+"/a.png?", true)
+	if q := checkSigned(t, "private process", pri.GenUrlProcess("a.png", process), host+"/a.png?", true); q.Get("x-oss-process") != process {
+		t.Fatalf("private process: %v", q)
+	}
+
+	// 签名失败时记录日志，返回不带签名的地址
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	bad := newSignClient(t, true, "")
+	bad.cfg.HostAlias = nil
+	if u := bad.GenUrl("a.png"); u != "a.png" {
+		t.Fatalf("fallback: %s", u)
+	}
+	if u := bad.GenUrlProcess("a.png", process); u != "a.png?x-oss-process="+process {
+		t.Fatalf("fallback process: %s", u)
+	}
+	if n := len(hook.AllEntries()); n != 2 {
+		t.Fatalf("fallback log: %d entries", n)
+	}
+	if e := hook.LastEntry(); e.Level != logrus.ErrorLevel || e.Data["key"] != "a.png" {
+		t.Fatalf("fallback log: %v %v", e.Level, e.Data)
+	}
+}
@@ -0,0 +1,117 @@
+package alioss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ObjectStore 对象存储，屏蔽具体的实现:
+//
+//	aliyun 阿里云 OSS ，即 AliOss
+//	s3     S3 兼容的存储，如 MinIO ，即 S3Store
+//	local  本地目录，用于开发与测试，即 LocalStore
+//
+// 对象路径 objectName 为完整路径，一般通过 UploadKey 生成
+type ObjectStore interface {
+	// UploadKey 上传后的文件对象路径
+	UploadKey(subKey string) string
+	// UploadKeyE 同 UploadKey ， subKey 包含「..」时报错
+	UploadKeyE(subKey string) (string, error)
+	// UploadFile 上传本地文件
+	UploadFile(localFile string, objectName string, opts ...UploadOption) error
+	// Upload 上传数据
+	Upload(ctx context.Context, objectName string, r io.Reader, opts ...UploadOption) error
+	// UploadLarge 分片上传本地的大文件
+	UploadLarge(ctx context.Context, objectName string, localFile string, opts ...UploadOption) error
+	// Download 下载，需要关闭
+	Download(ctx context.Context, objectName string) (io.ReadCloser, error)
+	// Delete 删除
+	Delete(ctx context.Context, objectName string) error
+	// List 按前缀列出对象， marker 为上次返回的 NextMarker
+	List(ctx context.Context, prefix, marker string, maxKeys int) (ListResult, error)
+	// Stat 对象信息
+	Stat(ctx context.Context, objectName string) (ObjectInfo, error)
+	// Presign 生成带签名的临时地址， method 为 GET 或 PUT
+	Presign(ctx context.Context, method string, objectName string, expire time.Duration) (string, error)
+	// GenUrl 完整的对外可访问的URL
+	GenUrl(key string) string
+	// GetPolicyToken 获取 上传授权 token ，用于客户端直传。失败时只记录日志并返回空的 token ，建议使用 GetPolicyTokenWith
+	GetPolicyToken(prefix, callbackBody string) PolicyToken
+	// GetPolicyTokenWith 按选项获取 上传授权 token
+	GetPolicyTokenWith(prefix string, opts PolicyOptions) (PolicyToken, error)
+}
+
+// ObjectInfo 对象信息
+type ObjectInfo struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag,omitempty"`
+	ContentType  string            `json:"content_type,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	Meta         map[string]string `json:"meta,omitempty"` // 自定义元信息
+}
+
+// ListResult 列出对象的结果
+type ListResult struct {
+	Objects     []ObjectInfo `json:"objects"`
+	NextMarker  string       `json:"next_marker,omitempty"` // 下一页的起点
+	IsTruncated bool         `json:"is_truncated"`          // 是否还有下一页
+}
+
+var (
+	_ ObjectStore = (*AliOss)(nil)
+	_ ObjectStore = (*S3Store)(nil)
+	_ ObjectStore = (*LocalStore)(nil)
+)
+
+// NewStore 根据配置 Driver 创建对象存储
+func NewStore(cfg AliOssConfig) (ObjectStore, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "aliyun", "oss":
+		return NewClient(cfg)
+	case "s3", "minio":
+		return NewS3Store(cfg)
+	case "local":
+		return NewLocalStore(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: [%s]", cfg.Driver)
+	}
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// storeBase 各实现共用的路径处理
+type storeBase struct {
+	cfg AliOssConfig
+}
+
+// UploadKey 上传后的文件对象路径，使用「/」拼接，且不会跳出 UploadDir （「..」被忽略）；
+// 外部传入的路径建议使用 UploadKeyE
+func (A *storeBase) UploadKey(subKey string) string {
+	if key, err := A.UploadKeyE(subKey); err == nil {
+		return key
+	}
+	return JoinKey(A.cfg.UploadDir, subKey)
+}
+
+// UploadKeyE 上传后的文件对象路径，参考 NormalizeKey ； subKey 包含「..」时报错
+func (A *storeBase) UploadKeyE(subKey string) (string, error) {
+	return joinSubKey(A.cfg.UploadDir, subKey)
+}
+
+// GenUrl 完整的对外可访问的URL
+func (A *storeBase) GenUrl(key string) string {
+	if A.cfg.Host == "" {
+		return key
+	}
+	if key == "" {
+		return ""
+	}
+	if strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://") {
+		return key
+	}
+	return PathJoin(A.cfg.Host, key)
+}
@@ -0,0 +1,216 @@
+package alioss
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// STS 临时授权，用于浏览器、小程序直传，避免下发长期的 AccessKey
+//
+//	https://help.aliyun.com/document_detail/28763.html AssumeRole
+//
+//	cli, _ := alioss.NewClient(cfg) // cfg.RoleArn = "acs:ram::123:role/oss-upload"
+//	cli.SetCredentialProvider(alioss.NewSTSProvider(cfg))
+//	token, err := cli.GetPolicyTokenWith("avatar/", alioss.PolicyOptions{})
+//	// token.SecurityToken 需作为表单字段 x-oss-security-token 提交（已放到 FormData）
+//
+//	测试时可以使用 CredentialFunc 替换
+
+// Credentials 临时授权
+type Credentials struct {
+	AccessKeyId     string    `json:"AccessKeyId"`
+	AccessKeySecret string    `json:"AccessKeySecret"`
+	SecurityToken   string    `json:"SecurityToken"`
+	Expiration      time.Time `json:"Expiration"` // 过期时间，必填，上传授权的有效期不超过此时间
+}
+
+// CredentialProvider 获取只能上传到 prefix 之下的临时授权
+type CredentialProvider interface {
+	Credentials(ctx context.Context, prefix string) (Credentials, error)
+}
+
+// CredentialFunc 函数形式的 CredentialProvider
+type CredentialFunc func(ctx context.Context, prefix string) (Credentials, error)
+
+func (f CredentialFunc) Credentials(ctx context.Context, prefix string) (Credentials, error) {
+	return f(ctx, prefix)
+}
+
+// SetCredentialProvider 设置临时授权， GetPolicyToken 使用临时授权签名。 nil 时使用配置中的 AccessKey
+func (A *AliOss) SetCredentialProvider(p CredentialProvider) {
+	A.creds = p
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+const (
+	DefaultStsEndPoint = "sts.aliyuncs.com"
+	DefaultStsDuration = 3600 // 秒
+)
+
+// STSProvider 通过 AssumeRole 获取临时授权，按 prefix 缓存到临近过期
+type STSProvider struct {
+	cfg    AliOssConfig
+	client *http.Client
+
+	// 剩余有效期少于该值时重新获取，默认为有效期的 1/5
+	RefreshBefore time.Duration
+
+	mu    sync.Mutex
+	cache map[string]Credentials
+}
+
+// NewSTSProvider 创建，使用配置中的 AccessKey 扮演 RoleArn
+func NewSTSProvider(cfg AliOssConfig) *STSProvider {
+	if cfg.StsEndPoint == "" {
+		cfg.StsEndPoint = DefaultStsEndPoint
+	}
+	if cfg.StsDuration <= 0 {
+		cfg.StsDuration = DefaultStsDuration
+	}
+	return &STSProvider{
+		cfg:           cfg,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		RefreshBefore: time.Duration(cfg.StsDuration) * time.Second / 5,
+		cache:         map[string]Credentials{},
+	}
+}
+
+// SetHTTPClient 替换 http 客户端
+func (P *STSProvider) SetHTTPClient(client *http.Client) {
+	P.client = client
+}
+
+// Credentials 获取临时授权
+func (P *STSProvider) Credentials(ctx context.Context, prefix string) (Credentials, error) {
+	P.mu.Lock()
+	defer P.mu.Unlock()
+	if c, ok := P.cache[prefix]; ok && time.Until(c.Expiration) > P.RefreshBefore {
+		return c, nil
+	}
+	c, err := P.assumeRole(ctx, prefix)
+	if err != nil {
+		return c, err
+	}
+	// 清理过期的
+	for k, v := range P.cache {
+		if time.Until(v.Expiration) <= P.RefreshBefore {
+			delete(P.cache, k)
+		}
+	}
+	P.cache[prefix] = c
+	return c, nil
+}
+
+// policy 只允许上传到 prefix 之下
+func (P *STSProvider) policy(prefix string) string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"Version": "1",
+		"Statement": []map[string]interface{}{{
+			"Effect":   "Allow",
+			"Action":   []string{"oss:PutObject"},
+			"Resource": []string{fmt.Sprintf("acs:oss:*:*:%s/%s*", P.cfg.Bucket, prefix)},
+		}},
+	})
+	return string(b)
+}
+
+func (P *STSProvider) assumeRole(ctx context.Context, prefix string) (Credentials, error) {
+	var res struct {
+		Credentials Credentials
+		Code        string
+		Message     string
+	}
+	if P.cfg.RoleArn == "" {
+		return res.Credentials, fmt.Errorf("未配置 RoleArn")
+	}
+	sessionName := P.cfg.StsSessionName
+	if sessionName == "" {
+		sessionName = "upload"
+	}
+	params := url.Values{}
+	params.Set("Format", "JSON")
+	params.Set("Version", "2015-04-01")
+	params.Set("AccessKeyId", P.cfg.AccessKeyId)
+	params.Set("SignatureMethod", "HMAC-SHA1")
+	params.Set("SignatureVersion", "1.0")
+	params.Set("SignatureNonce", nonce())
+	params.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	params.Set("Action", "AssumeRole")
+	params.Set("RoleArn", P.cfg.RoleArn)
+	params.Set("RoleSessionName", sessionName)
+	params.Set("DurationSeconds", strconv.Itoa(P.cfg.StsDuration))
+	params.Set("Policy", P.policy(prefix))
+	params.Set("Signature", rpcSign(http.MethodGet, params, P.cfg.AccessKeySecret))
+
+	endpoint := P.cfg.StsEndPoint
+	if !isURL(endpoint) {
+		endpoint = "https://" + endpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpoint, "/")+"/?"+params.Encode(), nil)
+	if err != nil {
+		return res.Credentials, err
+	}
+	resp, err := P.client.Do(req)
+	if err != nil {
+		return res.Credentials, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return res.Credentials, err
+	}
+	if err = json.Unmarshal(body, &res); err != nil {
+		return res.Credentials, fmt.Errorf("sts 返回无效: %d %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || res.Credentials.AccessKeyId == "" {
+		return res.Credentials, fmt.Errorf("sts 失败: %d %s %s", resp.StatusCode, res.Code, res.Message)
+	}
+	return res.Credentials, nil
+}
+
+// rpcSign 阿里云 RPC 风格的签名
+//
+//	https://help.aliyun.com/document_detail/315526.html
+func rpcSign(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params.Get(k)))
+	}
+	stringToSign := method + "&%2F&" + percentEncode(strings.Join(pairs, "&"))
+	h := hmac.New(sha1.New, []byte(secret+"&"))
+	_, _ = io.WriteString(h, stringToSign)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package alioss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSTSProvider(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		q := r.URL.Query()
+		sign := q.Get("Signature")
+		q.Del("Signature")
+		if sign != rpcSign(http.MethodGet, q, "secret") || q.Get("Action") != "AssumeRole" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"Code":"SignatureDoesNotMatch"}`))
+			return
+		}
+		if !strings.Contains(q.Get("Policy"), "acs:oss:*:*:j00/demo/a*") {
+			t.Errorf("policy: %s", q.Get("Policy"))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Credentials": Credentials{
+			AccessKeyId:     "STS.id",
+			AccessKeySecret: "sts-secret",
+			SecurityToken:   "token",
+			Expiration:      time.Now().Add(time.Hour).UTC(),
+		}})
+	}))
+	defer srv.Close()
+
+	cfg := AliOssConfig{
+		AccessKeyId:     "id",
+		AccessKeySecret: "secret",
+		EndPoint:        "oss-cn-shenzhen.aliyuncs.com",
+		Bucket:          "j00",
+		UploadDir:       "demo",
+		RoleArn:         "acs:ram::1:role/upload",
+		StsEndPoint:     srv.URL,
+	}
+	p := NewSTSProvider(cfg)
+	for i := 0; i < 2; i++ {
+		c, err := p.Credentials(context.Background(), "demo/a")
+		if err != nil || c.AccessKeyId != "STS.id" {
+			t.Fatalf("credentials: %+v %v", c, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("cache: %d calls", calls)
+	}
+
+	cli, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.SetCredentialProvider(CredentialFunc(func(ctx context.Context, prefix string) (Credentials, error) {
+		return Credentials{AccessKeyId: "fake", AccessKeySecret: "s", SecurityToken: "tk", Expiration: time.Now().Add(10 * time.Second)}, nil
+	}))
+	token, err := cli.GetPolicyTokenWith("a", PolicyOptions{Expire: time.Minute})
+	if err != nil || token.AccessKeyId != "fake" || token.SecurityToken != "tk" || token.FormData["x-oss-security-token"] != "tk" {
+		t.Fatalf("token: %+v %v", token, err)
+	}
+	if token.Expire > time.Now().Add(10*time.Second).Unix() {
+		t.Fatalf("expire: %d", token.Expire)
+	}
+
+	// 临时授权已过期，或没有有效期
+	for _, exp := range []time.Time{time.Now().Add(-time.Minute), time.Now().Add(100 * time.Millisecond), {}} {
+		exp := exp
+		cli.SetCredentialProvider(CredentialFunc(func(ctx context.Context, prefix string) (Credentials, error) {
+			return Credentials{AccessKeyId: "fake", AccessKeySecret: "s", SecurityToken: "tk", Expiration: exp}, nil
+		}))
+		if token, err = cli.GetPolicyTokenWith("a", PolicyOptions{}); err == nil {
+			t.Fatalf("expect expired error: %s %+v", exp, token)
+		}
+	}
+
+	// 获取临时授权失败
+	cli.SetCredentialProvider(CredentialFunc(func(ctx context.Context, prefix string) (Credentials, error) {
+		return Credentials{}, errors.New("sts down")
+	}))
+	if _, err = cli.GetPolicyTokenWith("a", PolicyOptions{}); err == nil {
+		t.Fatal("expect sts error")
+	}
+	hook := test.NewGlobal()
+	if token = cli.GetPolicyToken("a", ""); token.AccessKeyId != "" || token.Policy != "" {
+		t.Fatalf("legacy token: %+v", token)
+	}
+	if e := hook.LastEntry(); e == nil || e.Level != logrus.ErrorLevel || e.Data[logrus.ErrorKey] == nil {
+		t.Fatalf("legacy token log: %+v", e)
+	}
+}
@@ -0,0 +1,235 @@
+package alioss
+
+import (
+	"bytes"
+	"context"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"mime"
+	"mime/multipart"
+	"path"
+	"sync"
+)
+
+// 上传选项
+//
+//	示例:
+//
+//	   err := store.Upload(ctx, key, r,
+//	       alioss.WithContentType("image/png"),
+//	       alioss.WithMeta("uid", "1001"),
+//	       alioss.WithACL(alioss.ACLPublicRead),
+//	   )
+//
+//	   // 大文件分片上传，aliyun 支持断点续传
+//	   err := store.UploadLarge(ctx, key, "/tmp/big.zip",
+//	       alioss.WithPartSize(16<<20),
+//	       alioss.WithConcurrency(4),
+//	       alioss.WithProgress(func(consumed, total int64) { ... }),
+//	   )
+
+const (
+	DefaultPartSize    = 8 << 20 // 默认分片大小
+	DefaultConcurrency = 3       // 默认分片并发数
+)
+
+// 对象的访问权限
+const (
+	ACLDefault         = "default" // 继承 bucket
+	ACLPrivate         = "private"
+	ACLPublicRead      = "public-read"
+	ACLPublicReadWrite = "public-read-write"
+)
+
+// ProgressFunc 上传进度， total 未知时为 0 或 -1
+type ProgressFunc func(consumed, total int64)
+
+// UploadOptions 上传选项
+type UploadOptions struct {
+	ContentType        string            // 为空时按扩展名判断
+	ContentDisposition string            // 如 attachment; filename="a.txt"
+	Meta               map[string]string // 自定义元信息， LocalStore 忽略
+	ACL                string            // 访问权限，参考 ACLPrivate 等， LocalStore 忽略
+	Size               int64             // 数据长度， <=0 为未知
+
+	PartSize      int64        // 分片大小，默认 DefaultPartSize
+	Concurrency   int          // 分片并发数，默认 DefaultConcurrency
+	Progress      ProgressFunc // 进度回调
+	CheckpointDir string       // 断点续传记录的目录，为空时与文件同目录。仅 aliyun
+}
+
+// UploadOption 设置上传选项
+type UploadOption func(o *UploadOptions)
+
+func WithContentType(contentType string) UploadOption {
+	return func(o *UploadOptions) { o.ContentType = contentType }
+}
+
+func WithContentDisposition(value string) UploadOption {
+	return func(o *UploadOptions) { o.ContentDisposition = value }
+}
+
+func WithMeta(key, value string) UploadOption {
+	return func(o *UploadOptions) {
+		if o.Meta == nil {
+			o.Meta = map[string]string{}
+		}
+		o.Meta[key] = value
+	}
+}
+
+func WithACL(acl string) UploadOption {
+	return func(o *UploadOptions) { o.ACL = acl }
+}
+
+func WithSize(size int64) UploadOption {
+	return func(o *UploadOptions) { o.Size = size }
+}
+
+func WithPartSize(size int64) UploadOption {
+	return func(o *UploadOptions) { o.PartSize = size }
+}
+
+func WithConcurrency(n int) UploadOption {
+	return func(o *UploadOptions) { o.Concurrency = n }
+}
+
+func WithProgress(fn ProgressFunc) UploadOption {
+	return func(o *UploadOptions) { o.Progress = fn }
+}
+
+func WithCheckpointDir(dir string) UploadOption {
+	return func(o *UploadOptions) { o.CheckpointDir = dir }
+}
+
+func newUploadOptions(objectName string, opts []UploadOption) UploadOptions {
+	var o UploadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ContentType == "" {
+		o.ContentType = mime.TypeByExtension(path.Ext(objectName))
+	}
+	if o.PartSize <= 0 {
+		o.PartSize = DefaultPartSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+// UploadBytes 上传字节数据
+func UploadBytes(ctx context.Context, store ObjectStore, objectName string, data []byte, opts ...UploadOption) error {
+	opts = append([]UploadOption{WithSize(int64(len(data)))}, opts...)
+	return store.Upload(ctx, objectName, bytes.NewReader(data), opts...)
+}
+
+// UploadFileHeader 上传表单中的文件，默认使用表单中的 Content-Type
+func UploadFileHeader(ctx context.Context, store ObjectStore, objectName string, fh *multipart.FileHeader, opts ...UploadOption) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	pre := []UploadOption{WithSize(fh.Size)}
+	if ct := fh.Header.Get("Content-Type"); ct != "" && ct != "application/octet-stream" {
+		pre = append(pre, WithContentType(ct))
+	}
+	return store.Upload(ctx, objectName, f, append(pre, opts...)...)
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// ossOptions 转为 aliyun 的选项
+func (o UploadOptions) ossOptions() []oss.Option {
+	var opts []oss.Option
+	if o.ContentType != "" {
+		opts = append(opts, oss.ContentType(o.ContentType))
+	}
+	if o.ContentDisposition != "" {
+		opts = append(opts, oss.ContentDisposition(o.ContentDisposition))
+	}
+	for k, v := range o.Meta {
+		opts = append(opts, oss.Meta(k, v))
+	}
+	if o.ACL != "" {
+		opts = append(opts, oss.ObjectACL(oss.ACLType(o.ACL)))
+	}
+	if o.Progress != nil {
+		opts = append(opts, oss.Progress(ossProgress(o.Progress)))
+	}
+	return opts
+}
+
+type ossProgress ProgressFunc
+
+func (p ossProgress) ProgressChanged(e *oss.ProgressEvent) {
+	if e.EventType == oss.TransferDataEvent || e.EventType == oss.TransferCompletedEvent {
+		p(e.ConsumedBytes, e.TotalBytes)
+	}
+}
+
+// s3Options 转为 minio 的选项
+func (o UploadOptions) s3Options() minio.PutObjectOptions {
+	opts := minio.PutObjectOptions{
+		ContentType:        o.ContentType,
+		ContentDisposition: o.ContentDisposition,
+		UserMetadata:       map[string]string{},
+		PartSize:           uint64(o.PartSize),
+		NumThreads:         uint(o.Concurrency),
+	}
+	for k, v := range o.Meta {
+		opts.UserMetadata[k] = v
+	}
+	if o.ACL != "" && o.ACL != ACLDefault {
+		opts.UserMetadata["x-amz-acl"] = o.ACL
+	}
+	if o.Progress != nil {
+		opts.Progress = &progressSink{fn: o.Progress, total: o.Size}
+	}
+	return opts
+}
+
+// progressSink minio 通过读取 Progress 报告已上传的字节数
+type progressSink struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	consumed int64
+	total    int64
+}
+
+func (p *progressSink) Read(b []byte) (int, error) {
+	p.add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *progressSink) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.consumed += n
+	p.fn(p.consumed, p.total)
+}
+
+// progressReader 读取时报告进度
+type progressReader struct {
+	io.Reader
+	sink *progressSink
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	if n > 0 {
+		r.sink.add(int64(n))
+	}
+	return n, err
+}
+
+// wrapProgress 需要时包装 reader 以报告进度
+func (o UploadOptions) wrapProgress(r io.Reader) io.Reader {
+	if o.Progress == nil {
+		return r
+	}
+	return &progressReader{Reader: r, sink: &progressSink{fn: o.Progress, total: o.Size}}
+}
@@ -1,6 +1,13 @@
 package errno
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	pkgerrors "github.com/pkg/errors"
+	"io"
+	"runtime"
+	"sort"
+)
 
 // 2xx成功
 // 3xx重定向
@@ -31,33 +38,146 @@ func (e Errno) Error() string {
 	return e.Message
 }
 
+// Is 按错误码比较，用于 errors.Is
+//
+//	errors.Is(err, errno.QueryNotFound)
+func (e Errno) Is(target error) bool {
+	c, ok := target.(coder)
+	if !ok {
+		return false
+	}
+	code, _ := c.errnoCode()
+	return e.Code == code
+}
+
+func (e Errno) errnoCode() (int, string) {
+	return e.Code, e.Message
+}
+
+// coder Errno 与 Err 共用，用于在错误链中查找
+type coder interface {
+	errnoCode() (int, string)
+}
+
 // Err 带链
+//
+//	创建时记录调用栈；Fields 为排查用的详细字段（如出错的 id ），只用于日志，不返回给客户端
+//
+//	   return errno.QueryNotFound.WithErr(err, "user_id", id)
 type Err struct {
 	Errno
-	Err error
+	Err    error
+	Params map[string]interface{} // 消息模板的参数，参考 Localize
+	Fields map[string]interface{} // 详细字段，参考 FieldsOf
+
+	stack pkgerrors.StackTrace
 }
 
+func (err Err) params() map[string]interface{} {
+	return err.Params
+}
+
+// Error 包括描述、错误码与错误链，如: 参数有误 [400]: strconv.Atoi: invalid syntax
 func (err Err) Error() string {
-	return fmt.Sprintf("Err - code:%d", err.Code)
+	msg := render(err.Message, err.Params)
+	if err.Err == nil {
+		return fmt.Sprintf("%s [%d]", msg, err.Code)
+	}
+	return fmt.Sprintf("%s [%d]: %v", msg, err.Code, err.Err)
+}
+
+// Unwrap 错误链，用于 errors.Is 、 errors.As
+func (err Err) Unwrap() error {
+	return err.Err
+}
+
+// StackTrace 创建时的调用栈，与 github.com/pkg/errors 相同
+func (err Err) StackTrace() pkgerrors.StackTrace {
+	return err.stack
+}
+
+// With 追加详细字段，参数为 key, value 交替
+func (err *Err) With(kv ...interface{}) *Err {
+	err.Fields = mergeFields(err.Fields, kv)
+	return err
+}
+
+// Format 支持 %+v ：输出错误、详细字段与调用栈
+func (err Err) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, err.Error())
+		keys := make([]string, 0, len(err.Fields))
+		for k := range err.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(s, " %s=%v", k, err.Fields[k])
+		}
+		err.stack.Format(s, verb)
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", err.Error())
+	default:
+		io.WriteString(s, err.Error())
+	}
 }
 
 // DecodeErr 取出错误码与描述（不含错误链）。 默认返回 OK 的信息
+//
+//	沿错误链查找，如 fmt.Errorf("...: %w", errno.BadRequest) 返回 BadRequest
 func DecodeErr(err error) (int, string) {
+	code, message, params := decodeErr(err)
+	return code, render(message, params)
+}
+
+func decodeErr(err error) (int, string, map[string]interface{}) {
 	if err == nil {
-		return OK.Code, OK.Message
+		return OK.Code, OK.Message, nil
 	}
-	switch typed := err.(type) {
-	case *Err:
-		return typed.Code, typed.Message
-	case Err:
-		return typed.Code, typed.Message
-	case *Errno:
-		return typed.Code, typed.Message
-	case Errno:
-		return typed.Code, typed.Message
-	default:
+	var c coder
+	if errors.As(err, &c) {
+		code, message := c.errnoCode()
+		var params map[string]interface{}
+		if p, ok := c.(interface{ params() map[string]interface{} }); ok {
+			params = p.params()
+		}
+		return code, message, params
+	}
+	return InternalServerError.Code, err.Error(), nil
+}
+
+// FieldsOf 合并错误链中所有 Err 的详细字段，外层的优先
+func FieldsOf(err error) map[string]interface{} {
+	var fields map[string]interface{}
+	for ; err != nil; err = errors.Unwrap(err) {
+		e, ok := err.(*Err)
+		if !ok || len(e.Fields) == 0 {
+			continue
+		}
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		for k, v := range e.Fields {
+			if _, ok := fields[k]; !ok {
+				fields[k] = v
+			}
+		}
+	}
+	return fields
+}
+
+// StackOf 错误链中最内层的调用栈（最接近出错的位置），包括 github.com/pkg/errors 创建的错误
+func StackOf(err error) pkgerrors.StackTrace {
+	var stack pkgerrors.StackTrace
+	for ; err != nil; err = errors.Unwrap(err) {
+		if st, ok := err.(interface{ StackTrace() pkgerrors.StackTrace }); ok {
+			if s := st.StackTrace(); len(s) > 0 {
+				stack = s
+			}
+		}
 	}
-	return InternalServerError.Code, err.Error()
+	return stack
 }
 
 // -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
@@ -67,57 +187,96 @@ func (e Errno) SetMsg(msg string) Errno {
 	return Errno{e.Code, msg}
 }
 
-// WithErr 合并添加错误链，保留当前的错误码与描述
-func (e Errno) WithErr(err error) *Err {
-	return NewErr(e.Code, e.Message, err)
+// WithErr 合并添加错误链，保留当前的错误码与描述； kv 为详细字段， key, value 交替
+func (e Errno) WithErr(err error, kv ...interface{}) *Err {
+	return newErr(e, err, kv)
 }
 
 // WithMsg 合并添加错误链，保留当前的错误码，重新定义错误描述
-func (e Errno) WithMsg(msg string, err error) *Err {
-	return NewErr(e.Code, msg, err)
+func (e Errno) WithMsg(msg string, err error, kv ...interface{}) *Err {
+	return newErr(Errno{e.Code, msg}, err, kv)
+}
+
+// WithParams 设置消息模板的参数，替换描述中的 {name}
+func (e Errno) WithParams(params map[string]interface{}, kv ...interface{}) *Err {
+	err := newErr(e, nil, kv)
+	err.Params = params
+	return err
 }
 
+// isInner 错误链中是否有当前包内的错误
 func (e Errno) isInner(err error) bool {
-	switch err.(type) {
-	case *Err:
-		return true
-	case Err:
-		return true
-	case Errno:
-		return true
-	case *Errno:
-		return true
-	}
-	return false
+	return IsErrno(err)
+}
+
+// IsErrno 错误链中是否有当前包内的错误（ Errno 、 Err 、 MultiErr ）
+func IsErrno(err error) bool {
+	var c coder
+	return errors.As(err, &c)
 }
 
 // WithErr2 如果 err 是当前包内的错误，则直接引用；
 // 否则创建新对象：合并添加错误链，保留当前的错误码和描述
-func (e Errno) WithErr2(err error) error {
+func (e Errno) WithErr2(err error, kv ...interface{}) error {
 	if e.isInner(err) {
 		return err
 	}
-	return NewErr(e.Code, e.Message, err)
+	return newErr(e, err, kv)
 }
 
 // WithMsg2 如果 err 是当前包内的错误，则直接引用；
 // 否则创建新对象：合并添加错误链，保留当前的错误码，重新定义错误描述
-func (e Errno) WithMsg2(msg string, err error) error {
+func (e Errno) WithMsg2(msg string, err error, kv ...interface{}) error {
 	if e.isInner(err) {
 		return err
 	}
-	return NewErr(e.Code, msg, err)
+	return newErr(Errno{e.Code, msg}, err, kv)
 }
 
 // NewErr 创建一个新的错误对象，包括错误链
-func NewErr(code int, message string, err error) *Err {
-	return &Err{Errno{code, message}, err}
+func NewErr(code int, message string, err error, kv ...interface{}) *Err {
+	return newErr(Errno{code, message}, err, kv)
+}
+
+func NewErr2(errno *Errno, message string, err error, kv ...interface{}) *Err {
+	return newErr(Errno{errno.Code, errno.Message + "," + message}, err, kv)
 }
 
-func NewErr2(errno *Errno, message string, err error) *Err {
-	return &Err{Errno{errno.Code, errno.Message + "," + message}, err}
+func New(errno *Errno, err error, kv ...interface{}) *Err {
+	return newErr(*errno, err, kv)
 }
 
-func New(errno *Errno, err error) *Err {
-	return &Err{Errno{errno.Code, errno.Message}, err}
+// newErr 只能由导出的函数直接调用，以便跳过固定的层数
+func newErr(e Errno, err error, kv []interface{}) *Err {
+	return &Err{Errno: e, Err: err, Fields: mergeFields(nil, kv), stack: callers(2)}
+}
+
+// callers 调用栈， skip 为跳过的层数（不含 callers 本身）
+func callers(skip int) pkgerrors.StackTrace {
+	var pcs [32]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	st := make(pkgerrors.StackTrace, n)
+	for i := 0; i < n; i++ {
+		st[i] = pkgerrors.Frame(pcs[i])
+	}
+	return st
+}
+
+// mergeFields 把 key, value 交替的参数合并到 fields ；缺少 value 时记为 (MISSING)
+func mergeFields(fields map[string]interface{}, kv []interface{}) map[string]interface{} {
+	if len(kv) == 0 {
+		return fields
+	}
+	if fields == nil {
+		fields = make(map[string]interface{}, (len(kv)+1)/2)
+	}
+	for i := 0; i < len(kv); i += 2 {
+		k := fmt.Sprint(kv[i])
+		if i+1 < len(kv) {
+			fields[k] = kv[i+1]
+		} else {
+			fields[k] = "(MISSING)"
+		}
+	}
+	return fields
 }
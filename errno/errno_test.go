@@ -0,0 +1,151 @@
+package errno
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	err := fmt.Errorf("load user: %w", QueryNotFound.WithErr(io.EOF))
+	if code, msg := DecodeErr(err); code != QueryNotFound.Code || msg != QueryNotFound.Message {
+		t.Fatalf("DecodeErr = %d %s", code, msg)
+	}
+	if code, _ := DecodeErr(fmt.Errorf("bind: %w", BadRequest)); code != BadRequest.Code {
+		t.Fatalf("DecodeErr wrapped Errno = %d", code)
+	}
+	if code, _ := DecodeErr(io.EOF); code != InternalServerError.Code {
+		t.Fatalf("DecodeErr plain = %d", code)
+	}
+	// 返回给客户端时不包含原错误
+	if code, msg := Localize(fmt.Errorf("sql: %w", io.EOF), ""); code != InternalServerError.Code || msg != InternalServerError.Message {
+		t.Fatalf("Localize plain = %d %s", code, msg)
+	}
+
+	if !errors.Is(err, QueryNotFound) || !errors.Is(err, io.EOF) || errors.Is(err, BadRequest) {
+		t.Fatal("errors.Is")
+	}
+	if !errors.Is(BadRequest.SetMsg("缺少 id"), BadRequest) {
+		t.Fatal("errors.Is by code")
+	}
+	var e *Err
+	if !errors.As(err, &e) || e.Err != io.EOF {
+		t.Fatal("errors.As")
+	}
+
+	if s := QueryNotFound.WithErr(io.EOF).Error(); s != "记录并不存在 [550]: EOF" {
+		t.Fatalf("Error() = %s", s)
+	}
+	if got := FailedUpdate.WithErr2(fmt.Errorf("x: %w", Forbidden)); !errors.Is(got, Forbidden) {
+		t.Fatalf("WithErr2 = %v", got)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	scope := NewScope("test", 90000, 90099)
+	notFound := scope.Define(90001, "用户 {id} 不存在", HTTPStatus(404), Locale("en", "user {id} not found"))
+
+	err := fmt.Errorf("x: %w", notFound.WithParams(map[string]interface{}{"id": 7}))
+	if code, msg := DecodeErr(err); code != 90001 || msg != "用户 7 不存在" {
+		t.Fatalf("DecodeErr = %d %s", code, msg)
+	}
+	if _, msg := Localize(err, "en-US"); msg != "user 7 not found" {
+		t.Fatalf("Localize = %s", msg)
+	}
+	if _, msg := Localize(notFound.WithMsg("自定义", nil), "en"); msg != "自定义" {
+		t.Fatalf("Localize custom = %s", msg)
+	}
+	if StatusOf(err) != 404 || StatusOf(BadRequest) != 200 {
+		t.Fatal("StatusOf")
+	}
+
+	mustPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expect panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("duplicate", func() { scope.Define(90001, "重复") })
+	mustPanic("range", func() { scope.Define(90100, "超出") })
+	mustPanic("overlap", func() { NewScope("other", 90050, 90200) })
+	mustPanic("builtin", func() { NewScope("common2", 500, 600) })
+
+	var b strings.Builder
+	if err := ExportMarkdown(&b); err != nil || !strings.Contains(b.String(), "| 90001 | test | 404 | 用户 {id} 不存在 | user {id} not found |") {
+		t.Fatalf("ExportMarkdown: %v\n%s", err, b.String())
+	}
+}
+
+func TestDetail(t *testing.T) {
+	inner := QueryNotFound.WithErr(io.EOF, "user_id", 7, "table")
+	err := fmt.Errorf("load: %w", InternalServerError.WithErr(inner, "user_id", 8))
+	fields := FieldsOf(err)
+	if fields["user_id"] != 8 || fields["table"] != "(MISSING)" {
+		t.Fatalf("FieldsOf = %v", fields)
+	}
+	stack := StackOf(err)
+	if len(stack) == 0 || !strings.HasSuffix(fmt.Sprintf("%n", stack[0]), "TestDetail") {
+		t.Fatalf("StackOf = %v", stack)
+	}
+	s := fmt.Sprintf("%+v", inner)
+	if !strings.HasPrefix(s, "记录并不存在 [550]: EOF table=(MISSING) user_id=7\n") || !strings.Contains(s, "errno_test.go") {
+		t.Fatalf("%%+v = %s", s)
+	}
+	if fmt.Sprint(inner) != inner.Error() {
+		t.Fatal("Sprint")
+	}
+	if _, msg := DecodeErr(err); msg != InternalServerError.Message {
+		t.Fatalf("DecodeErr = %s", msg)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	type Item struct {
+		Name string `json:"name" binding:"required"`
+	}
+	type Base struct {
+		ID int `binding:"min=1"`
+	}
+	type Req struct {
+		Base
+		Items []*Item `json:"items" binding:"dive"`
+		Kind  string  `json:"kind,omitempty" binding:"oneof=a b"`
+		Age   int     `json:"age"`
+	}
+	v := validator.New()
+	v.SetTagName("binding")
+	req := &Req{Items: []*Item{{Name: "x"}, {}}, Kind: "c"}
+	m := Validation(v.Struct(req), req)
+	want := []Violation{
+		{Field: "ID", Rule: "min", Param: "1", Message: "不能小于 1"},
+		{Field: "items[1].name", Rule: "required", Message: "不能为空"},
+		{Field: "kind", Rule: "oneof", Param: "a b", Message: "应为 a b 之一"},
+	}
+	if !reflect.DeepEqual(m.Violations, want) {
+		t.Fatalf("Violations = %+v", m.Violations)
+	}
+	if code, _ := DecodeErr(fmt.Errorf("x: %w", m)); code != BadRequest.Code || DataOf(m) == nil {
+		t.Fatal("DecodeErr / DataOf")
+	}
+
+	err := json.Unmarshal([]byte(`{"items":[{"name":1}]}`), req)
+	m = Validation(err, req)
+	if len(m.Violations) != 1 || !strings.HasPrefix(m.Violations[0].Field, "items") || m.Violations[0].Rule != "type" {
+		t.Fatalf("UnmarshalTypeError = %+v", m.Violations)
+	}
+
+	m = BadRequest.Multi().Add(io.EOF).AddViolation("start", "lte", "不能晚于结束时间")
+	if !errors.Is(m, io.EOF) || !errors.Is(m, BadRequest) || m.Error() != "参数有误 [400]: start: 不能晚于结束时间; EOF" {
+		t.Fatalf("MultiErr = %v", m)
+	}
+	if BadRequest.Multi().ErrOrNil() != nil {
+		t.Fatal("ErrOrNil")
+	}
+}
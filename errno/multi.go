@@ -0,0 +1,284 @@
+package errno
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 多个错误与字段校验错误
+//
+//	字段校验错误返回在 Response.Data 中，前端可以按 field 定位到字段:
+//
+//	   {"code": 400, "message": "参数有误", "data": {"violations": [
+//	       {"field": "items[0].name", "rule": "required", "message": "不能为空"}
+//	   ]}}
+//
+//	由绑定参数的错误创建，参考 Validation ；也可以手动添加:
+//
+//	   m := errno.BadRequest.Multi()
+//	   if req.Start > req.End {
+//	       m.AddViolation("start", "lte", "不能晚于结束时间")
+//	   }
+//	   return m.ErrOrNil()
+
+// Violation 字段的校验错误
+type Violation struct {
+	Field   string `json:"field"`           // 字段路径，使用 json 名称，如 items[0].name
+	Rule    string `json:"rule"`            // 规则，如 required 、 max ；类型错误为 type
+	Param   string `json:"param,omitempty"` // 规则的参数，如 max=10 中的 10
+	Message string `json:"message"`
+}
+
+// RuleMessages 规则对应的描述， {param} 替换为规则的参数；没有时为「格式有误」
+var RuleMessages = map[string]string{
+	"required": "不能为空",
+	"len":      "长度应为 {param}",
+	"min":      "不能小于 {param}",
+	"max":      "不能大于 {param}",
+	"gt":       "应大于 {param}",
+	"gte":      "不能小于 {param}",
+	"lt":       "应小于 {param}",
+	"lte":      "不能大于 {param}",
+	"eq":       "应等于 {param}",
+	"ne":       "不能等于 {param}",
+	"oneof":    "应为 {param} 之一",
+	"email":    "邮箱格式有误",
+	"url":      "网址格式有误",
+	"type":     "类型应为 {param}",
+}
+
+// InvalidMessage 无法对应到字段的参数错误（如 json 格式有误）返回的描述
+var InvalidMessage = "请求参数格式有误"
+
+// MultiErr 多个错误，包括字段校验错误
+type MultiErr struct {
+	Errno
+	Errors     []error
+	Violations []Violation
+}
+
+// Multi 创建多个错误的集合，使用当前的错误码与描述
+func (e Errno) Multi() *MultiErr {
+	return &MultiErr{Errno: e}
+}
+
+// Add 添加错误， MultiErr 会被展开；nil 忽略
+func (m *MultiErr) Add(errs ...error) *MultiErr {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if o, ok := err.(*MultiErr); ok {
+			m.Errors = append(m.Errors, o.Errors...)
+			m.Violations = append(m.Violations, o.Violations...)
+			continue
+		}
+		m.Errors = append(m.Errors, err)
+	}
+	return m
+}
+
+// AddViolation 添加字段校验错误
+func (m *MultiErr) AddViolation(field, rule, message string) *MultiErr {
+	m.Violations = append(m.Violations, Violation{Field: field, Rule: rule, Message: message})
+	return m
+}
+
+// Len 错误的数量
+func (m *MultiErr) Len() int {
+	return len(m.Errors) + len(m.Violations)
+}
+
+// ErrOrNil 没有错误时返回 nil
+func (m *MultiErr) ErrOrNil() error {
+	if m == nil || m.Len() == 0 {
+		return nil
+	}
+	return m
+}
+
+// Error 包括描述、错误码与所有错误，如: 参数有误 [400]: name: 不能为空; EOF
+func (m *MultiErr) Error() string {
+	list := make([]string, 0, m.Len())
+	for _, v := range m.Violations {
+		if v.Field == "" {
+			list = append(list, v.Message)
+		} else {
+			list = append(list, v.Field+": "+v.Message)
+		}
+	}
+	for _, err := range m.Errors {
+		list = append(list, err.Error())
+	}
+	if len(list) == 0 {
+		return fmt.Sprintf("%s [%d]", m.Message, m.Code)
+	}
+	return fmt.Sprintf("%s [%d]: %s", m.Message, m.Code, strings.Join(list, "; "))
+}
+
+// Is 错误码相同，或任一错误满足 errors.Is
+func (m *MultiErr) Is(target error) bool {
+	if m.Errno.Is(target) {
+		return true
+	}
+	for _, err := range m.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 任一错误满足 errors.As
+func (m *MultiErr) As(target interface{}) bool {
+	for _, err := range m.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Data 返回给客户端的内容：只有字段校验错误，其它错误只用于日志
+func (m *MultiErr) Data() interface{} {
+	if len(m.Violations) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"violations": m.Violations}
+}
+
+// DataOf 错误链中 MultiErr 返回给客户端的内容，参考 MultiErr.Data
+func DataOf(err error) interface{} {
+	var m *MultiErr
+	if errors.As(err, &m) {
+		return m.Data()
+	}
+	return nil
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// Validation 绑定参数的错误转为 BadRequest 的 MultiErr
+//
+//	validator.ValidationErrors 、 json.UnmarshalTypeError 转为字段校验错误，字段路径按 obj 的 json 名称；
+//	其它错误（如 json 格式有误）为无字段的 invalid ，描述固定为 InvalidMessage ，原错误只保留在 Errors 中用于日志（可能包含请求内容）
+func Validation(err error, obj interface{}) *MultiErr {
+	m := BadRequest.Multi()
+	m.addBindErr(err, reflect.TypeOf(obj))
+	return m
+}
+
+func (m *MultiErr) addBindErr(err error, t reflect.Type) {
+	var ves validator.ValidationErrors
+	var ute *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &ves):
+		for _, fe := range ves {
+			v := Violation{Field: jsonPath(t, fe.StructNamespace()), Rule: fe.Tag(), Param: fe.Param()}
+			v.Message = ruleMessage(v.Rule, v.Param)
+			m.Violations = append(m.Violations, v)
+		}
+	case errors.As(err, &ute):
+		typ := ute.Type.String()
+		m.Violations = append(m.Violations, Violation{Field: indexPath(ute.Field), Rule: "type", Param: typ, Message: ruleMessage("type", typ)})
+	default:
+		// 如 binding.SliceValidationError ，元素的下标已丢失
+		if rv := reflect.ValueOf(err); rv.Kind() == reflect.Slice && rv.Type().Elem() == errorType {
+			et := elemType(t)
+			if et != nil && (et.Kind() == reflect.Slice || et.Kind() == reflect.Array) {
+				et = et.Elem()
+			}
+			for i := 0; i < rv.Len(); i++ {
+				if e, ok := rv.Index(i).Interface().(error); ok && e != nil {
+					m.addBindErr(e, et)
+				}
+			}
+			return
+		}
+		m.Errors = append(m.Errors, err)
+		m.Violations = append(m.Violations, Violation{Rule: "invalid", Message: InvalidMessage})
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func ruleMessage(rule, param string) string {
+	msg, ok := RuleMessages[rule]
+	if !ok {
+		msg = "格式有误"
+	}
+	return strings.ReplaceAll(msg, "{param}", param)
+}
+
+// jsonPath 把 validator 的 StructNamespace （如 Req.Items[0].Name ）转为 json 路径（如 items[0].name ）
+func jsonPath(t reflect.Type, ns string) string {
+	segs := strings.Split(ns, ".")
+	if len(segs) > 1 {
+		segs = segs[1:] // 根结构的名称
+	}
+	t = elemType(t)
+	var b strings.Builder
+	for _, seg := range segs {
+		name, idx := seg, ""
+		if i := strings.IndexByte(seg, '['); i >= 0 {
+			name, idx = seg[:i], seg[i:]
+		}
+		jsonName, embedded := name, false
+		var ft reflect.Type
+		if t != nil && t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(name); ok {
+				ft = f.Type
+				tag := strings.Split(f.Tag.Get("json"), ",")[0]
+				switch {
+				case tag != "" && tag != "-":
+					jsonName = tag
+				case f.Anonymous:
+					embedded = true
+				}
+			}
+		}
+		if !embedded {
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(jsonName)
+		}
+		b.WriteString(idx)
+		t = elemType(ft)
+		for i := strings.Count(idx, "["); i > 0 && t != nil; i-- {
+			if k := t.Kind(); k == reflect.Slice || k == reflect.Array || k == reflect.Map {
+				t = elemType(t.Elem())
+			}
+		}
+	}
+	return b.String()
+}
+
+// indexPath encoding/json 的字段路径中，下标改为与 validator 一致的格式： items.0.name => items[0].name
+func indexPath(field string) string {
+	segs := strings.Split(field, ".")
+	var b strings.Builder
+	for i, seg := range segs {
+		if _, err := strconv.Atoi(seg); err == nil && i > 0 {
+			b.WriteString("[" + seg + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
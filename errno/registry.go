@@ -0,0 +1,256 @@
+package errno
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 错误码登记
+//
+//	各服务声明自己的错误码范围，在范围内定义错误码；范围重叠、错误码重复时 panic ，在 init 阶段即可发现
+//
+//	   var scope = errno.NewScope("user", 10000, 10999)
+//
+//	   var (
+//	       UserNotFound = scope.Define(10001, "用户 {id} 不存在",
+//	           errno.HTTPStatus(http.StatusNotFound),
+//	           errno.Locale("en", "user {id} not found"))
+//	   )
+//
+//	   return UserNotFound.WithParams(map[string]interface{}{"id": id})
+//
+//	消息中的 {name} 由 Params 替换，参考 Localize 。内置的错误码属于 common ，范围 0-999
+
+// Entry 登记的错误码
+type Entry struct {
+	Code       int               `json:"code"`
+	Service    string            `json:"service"`
+	Message    string            `json:"message"`
+	HTTPStatus int               `json:"http_status,omitempty"` // 0 表示默认的 200
+	Locales    map[string]string `json:"locales,omitempty"`     // 语言 => 消息模板
+}
+
+// Option 错误码的选项
+type Option func(e *Entry)
+
+// HTTPStatus 返回时使用的 HTTP 状态码，参考 ginkit.SendResponse
+func HTTPStatus(status int) Option {
+	return func(e *Entry) { e.HTTPStatus = status }
+}
+
+// Locale 某个语言的消息模板，如 en 、 zh-TW
+func Locale(lang, message string) Option {
+	return func(e *Entry) {
+		if e.Locales == nil {
+			e.Locales = map[string]string{}
+		}
+		e.Locales[strings.ToLower(lang)] = message
+	}
+}
+
+// Scope 服务的错误码范围
+type Scope struct {
+	Service  string
+	Min, Max int
+}
+
+type registry struct {
+	mu      sync.RWMutex
+	scopes  []*Scope
+	entries map[int]*Entry
+}
+
+var reg = &registry{entries: map[int]*Entry{}}
+
+// NewScope 声明服务的错误码范围 [min, max] ，与已有的范围重叠时 panic
+func NewScope(service string, min, max int) *Scope {
+	if min > max {
+		panic(fmt.Sprintf("errno: 无效的范围 %s [%d, %d]", service, min, max))
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for _, s := range reg.scopes {
+		if min <= s.Max && s.Min <= max {
+			panic(fmt.Sprintf("errno: %s [%d, %d] 与 %s [%d, %d] 重叠", service, min, max, s.Service, s.Min, s.Max))
+		}
+	}
+	s := &Scope{Service: service, Min: min, Max: max}
+	reg.scopes = append(reg.scopes, s)
+	return s
+}
+
+// Define 定义错误码，不在范围内或重复时 panic
+func (s *Scope) Define(code int, message string, opts ...Option) *Errno {
+	s.register(&Errno{code, message}, opts...)
+	return &Errno{code, message}
+}
+
+func (s *Scope) register(e *Errno, opts ...Option) {
+	if e.Code < s.Min || e.Code > s.Max {
+		panic(fmt.Sprintf("errno: %d 不在 %s 的范围 [%d, %d]", e.Code, s.Service, s.Min, s.Max))
+	}
+	entry := &Entry{Code: e.Code, Service: s.Service, Message: e.Message}
+	for _, opt := range opts {
+		opt(entry)
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if old, ok := reg.entries[e.Code]; ok {
+		panic(fmt.Sprintf("errno: %d 重复定义: %s 与 %s", e.Code, old.Message, e.Message))
+	}
+	reg.entries[e.Code] = entry
+}
+
+// Configure 修改已登记的错误码，如给内置的错误码增加 HTTP 状态码或其它语言
+func Configure(code int, opts ...Option) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	entry, ok := reg.entries[code]
+	if !ok {
+		return fmt.Errorf("errno: %d 未登记", code)
+	}
+	e := *entry
+	e.Locales = map[string]string{}
+	for k, v := range entry.Locales {
+		e.Locales[k] = v
+	}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	reg.entries[code] = &e
+	return nil
+}
+
+// Lookup 查找登记的错误码
+func Lookup(code int) (Entry, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	if e, ok := reg.entries[code]; ok {
+		return *e, true
+	}
+	return Entry{}, false
+}
+
+// StatusOf 错误对应的 HTTP 状态码，未设置时为 200
+func StatusOf(err error) int {
+	code, _ := DecodeErr(err)
+	if e, ok := Lookup(code); ok && e.HTTPStatus > 0 {
+		return e.HTTPStatus
+	}
+	return http.StatusOK
+}
+
+// Localize 取出错误码与指定语言的描述，并替换参数
+//
+//	lang 如 zh-CN ，依次查找 zh-cn 、 zh ；没有时使用原来的描述。
+//	自定义了描述（ WithMsg 等）时不替换为其它语言。
+//	用于返回给客户端：错误链中没有 errno 的错误时使用 InternalServerError 的描述，原错误只记录在日志中
+func Localize(err error, lang string) (int, string) {
+	code, message, params := decodeErr(err)
+	if err != nil && !IsErrno(err) {
+		code, message, params = InternalServerError.Code, InternalServerError.Message, nil
+	}
+	if entry, ok := Lookup(code); ok && entry.Message == message && lang != "" {
+		lang = strings.ToLower(lang)
+		for lang != "" {
+			if m, ok := entry.Locales[lang]; ok {
+				message = m
+				break
+			}
+			i := strings.LastIndexAny(lang, "-_")
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	return code, render(message, params)
+}
+
+// render 替换消息中的 {name}
+func render(message string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// Catalog 所有登记的错误码，按错误码排序
+func Catalog() []Entry {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	list := make([]Entry, 0, len(reg.entries))
+	for _, e := range reg.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// ExportJSON 导出错误码列表
+func ExportJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Catalog())
+}
+
+// ExportMarkdown 导出错误码表格，每种语言一列
+func ExportMarkdown(w io.Writer) error {
+	list := Catalog()
+	langSet := map[string]bool{}
+	for _, e := range list {
+		for l := range e.Locales {
+			langSet[l] = true
+		}
+	}
+	langs := make([]string, 0, len(langSet))
+	for l := range langSet {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	var b strings.Builder
+	b.WriteString("| 错误码 | 服务 | HTTP | 描述 |")
+	for _, l := range langs {
+		b.WriteString(" " + l + " |")
+	}
+	b.WriteString("\n|---|---|---|---|" + strings.Repeat("---|", len(langs)) + "\n")
+	for _, e := range list {
+		status := ""
+		if e.HTTPStatus > 0 {
+			status = fmt.Sprint(e.HTTPStatus)
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s |", e.Code, e.Service, status, mdEscape(e.Message))
+		for _, l := range langs {
+			b.WriteString(" " + mdEscape(e.Locales[l]) + " |")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func mdEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " ")
+}
+
+func init() {
+	common := NewScope("common", 0, 999)
+	for _, e := range []*Errno{
+		OK, BadRequest, Unauthorized, Forbidden, Conflict, TooManyRequests,
+		InternalServerError, QueryNotFound, QueryFailed, ConvertDataFailed, FailedUpdate,
+	} {
+		common.register(e)
+	}
+}
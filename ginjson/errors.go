@@ -0,0 +1,58 @@
+package ginjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// typeError 把 json-iterator 的字段解析错误转为 *json.UnmarshalTypeError ，不能转换时原样返回
+//
+//	json-iterator 的错误只有文本，如:
+//	  pkg.Req.Items: []*pkg.Item: pkg.Item.N: readUint64: unexpected character ..., error found in #1 byte of ...|zz|...
+//	按 obj 的类型逐段匹配，字段转为 json 名称（没有数组下标），如 items.n
+func typeError(obj interface{}, err error) error {
+	t := indirect(reflect.TypeOf(obj))
+	var names []string
+	var structName string
+	var fieldType reflect.Type
+	for _, seg := range strings.Split(err.Error(), ": ") {
+		if t == nil {
+			break
+		}
+		if t.Kind() == reflect.Struct && strings.HasPrefix(seg, t.String()+".") {
+			f, ok := t.FieldByName(strings.TrimPrefix(seg, t.String()+"."))
+			if !ok {
+				// 如 Req.isObjectEnd ，为格式错误
+				return err
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			names = append(names, name)
+			structName, fieldType = t.Name(), f.Type
+			t = indirect(f.Type)
+			continue
+		}
+		// 切片、数组、 map 的元素
+		if seg == t.String() {
+			if k := t.Kind(); k == reflect.Slice || k == reflect.Array || k == reflect.Map {
+				t = indirect(t.Elem())
+				continue
+			}
+		}
+		break
+	}
+	if len(names) == 0 {
+		return err
+	}
+	return &json.UnmarshalTypeError{Value: "value", Type: fieldType, Struct: structName, Field: strings.Join(names, ".")}
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
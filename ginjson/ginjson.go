@@ -64,7 +64,7 @@ func decodeJSON(r io.Reader, obj interface{}) error {
 		decoder.DisallowUnknownFields()
 	}
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return typeError(obj, err)
 	}
 	return validate(obj)
 }
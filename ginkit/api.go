@@ -1,16 +1,17 @@
 package ginkit
 
 import (
-	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/xtulnx/go-srv/errno"
 	"github.com/xtulnx/go-srv/ginjson"
+	"github.com/xtulnx/go-srv/logkit"
 	"github.com/xtulnx/go-srv/utils"
 	"mime"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strings"
 )
 
 type Response struct {
@@ -19,15 +20,40 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// SendResponse 返回结果
+//
+//	HTTP 状态码参考 errno.HTTPStatus ，默认 200 ；描述按 Accept-Language 选择语言，参考 errno.Localize
+//	5xx 时记录日志（含详细字段与调用栈，参考 logkit.LogErr ），返回中只有错误码与描述；
+//	data 为空时返回错误中的字段校验错误，参考 errno.DataOf
 func SendResponse(c *gin.Context, err error, data interface{}) {
-	code, message := errno.DecodeErr(err)
-	c.JSON(http.StatusOK, Response{
+	SendResponseStatus(c, errno.StatusOf(err), err, data)
+}
+
+// SendResponseStatus 与 SendResponse 相同，但使用指定的 HTTP 状态码
+func SendResponseStatus(c *gin.Context, status int, err error, data interface{}) {
+	code, message := errno.Localize(err, AcceptLanguage(c))
+	if code >= 500 {
+		logkit.LogErr(c, err)
+	}
+	if data == nil {
+		data = errno.DataOf(err)
+	}
+	c.JSON(status, Response{
 		Code:    code,
 		Message: message,
 		Data:    data,
 	})
 }
 
+// AcceptLanguage 客户端首选的语言，如 zh-CN
+func AcceptLanguage(c *gin.Context) string {
+	lang := c.GetHeader("Accept-Language")
+	if i := strings.IndexAny(lang, ",;"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.TrimSpace(lang)
+}
+
 func SendResp1(c *gin.Context, code int, message string, data interface{}) {
 	c.JSON(http.StatusOK, Response{Code: code, Message: message, Data: data})
 }
@@ -63,7 +89,8 @@ func SetResponseHeaderForFile(c *gin.Context, fileName, fileExt string) {
 	rh.Set("Content-Disposition", "attachment; filename="+url.QueryEscape(fileName))
 }
 
-// BindParam 解析参数
+// BindParam 解析参数。绑定失败时返回 errno.MultiErr ，包括每个字段的校验错误；
+// 之后依次调用 WithBindParam 、 WithCheckParam ，出错时返回
 func BindParam(c *gin.Context, obj interface{}, _log utils.Logger) error {
 	// err := c.Bind(obj)
 	// 兼容 json 2022.04.20
@@ -74,18 +101,7 @@ func BindParam(c *gin.Context, obj interface{}, _log utils.Logger) error {
 	err := c.MustBindWith(obj, b)
 	if err != nil {
 		_log.Error(err)
-		var msg string
-		if e, ok := err.(*json.UnmarshalTypeError); ok {
-			if e.Struct != "" && e.Field != "" {
-				msg = "无效参数" + ": [" + e.Value + "]" + " 字段: " + e.Struct + "." + e.Field + " 类型: " + e.Type.String()
-			} else {
-				msg = "无效参数" + ": [" + e.Value + "]" + " 类型: " + e.Type.String()
-			}
-			//} else if e,ok:=err.(*strconv.NumError); ok {
-		} else {
-			msg = "无效参数" + err.Error()
-		}
-		return errno.BadRequest.WithMsg(msg, err)
+		return errno.Validation(err, obj)
 	}
 
 	if iper, ok := obj.(WithIPer); ok {
@@ -94,12 +110,24 @@ func BindParam(c *gin.Context, obj interface{}, _log utils.Logger) error {
 
 	// 其他
 	if b1, ok := obj.(WithBindParam); ok {
-		err = b1.BindParam(c)
+		if err = b1.BindParam(c); err != nil {
+			return bindErr(err, obj)
+		}
 	}
 
 	if checker, ok := obj.(WithCheckParam); ok {
-		err = checker.CheckParam()
+		if err = checker.CheckParam(); err != nil {
+			return bindErr(err, obj)
+		}
 	}
 
 	return nil
 }
+
+// bindErr WithBindParam 、 WithCheckParam 返回的错误， errno 的错误原样返回，其它按校验错误处理
+func bindErr(err error, obj interface{}) error {
+	if errno.IsErrno(err) {
+		return err
+	}
+	return errno.Validation(err, obj)
+}
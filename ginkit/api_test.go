@@ -0,0 +1,130 @@
+package ginkit
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/xtulnx/go-srv/errno"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.GET("/", func(c *gin.Context) {
+		SendResponse(c, errors.New("dial tcp 10.0.0.1:3306: connection refused"), nil)
+	})
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != errno.InternalServerError.Code || resp.Message != errno.InternalServerError.Message {
+		t.Fatalf("response: %s", w.Body.String())
+	}
+}
+
+func TestBindParam(t *testing.T) {
+	type item struct {
+		N int `json:"n"`
+	}
+	type req struct {
+		Name  string  `json:"name" binding:"required"`
+		Age   int     `json:"age"`
+		Items []*item `json:"items"`
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.POST("/", func(c *gin.Context) {
+		var r req
+		SendResponse(c, BindParam(c, &r, logger), nil)
+	})
+
+	for body, want := range map[string]errno.Violation{
+		`{"name":"a","age":"x"}`:            {Field: "age", Rule: "type", Param: "int"},
+		`{"name":"a","items":[{"n":"zz"}]}`: {Field: "items.n", Rule: "type", Param: "int"},
+		`{"age":1}`:                         {Field: "name", Rule: "required"},
+		`{"name":"secret-value","age":1`:    {Rule: "invalid", Message: errno.InvalidMessage},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		g.ServeHTTP(w, r)
+		var resp struct {
+			Code int
+			Data struct{ Violations []errno.Violation }
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != errno.BadRequest.Code || len(resp.Data.Violations) != 1 {
+			t.Fatalf("%s: %s", body, w.Body.String())
+		}
+		v := resp.Data.Violations[0]
+		if v.Field != want.Field || v.Rule != want.Rule || v.Param != want.Param || (want.Message != "" && v.Message != want.Message) {
+			t.Fatalf("%s: %+v", body, v)
+		}
+		// 不返回解析器的错误文本与请求内容
+		if strings.Contains(w.Body.String(), "secret-value") || strings.Contains(w.Body.String(), "error found") {
+			t.Fatalf("%s: %s", body, w.Body.String())
+		}
+	}
+}
+
+type checkedReq struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (r *checkedReq) CheckParam() error {
+	if r.Name == "root" {
+		return errno.Forbidden
+	}
+	if r.Age < 0 {
+		return errors.New("age < 0")
+	}
+	return nil
+}
+
+func TestBindParamCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.POST("/", func(c *gin.Context) {
+		var r checkedReq
+		SendResponse(c, BindParam(c, &r, logger), nil)
+	})
+
+	for body, want := range map[string]struct {
+		code int
+		rule string
+	}{
+		`{"name":"a","age":1}`:    {errno.OK.Code, ""},
+		`{"name":"root","age":1}`: {errno.Forbidden.Code, ""},
+		`{"name":"a","age":-1}`:   {errno.BadRequest.Code, "invalid"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		g.ServeHTTP(w, r)
+		var resp struct {
+			Code int
+			Data struct{ Violations []errno.Violation }
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != want.code || (want.rule != "" && (len(resp.Data.Violations) != 1 || resp.Data.Violations[0].Rule != want.rule)) {
+			t.Fatalf("%s: %s", body, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package grpckit
+
+import (
+	"context"
+	"github.com/xtulnx/go-srv/errno"
+	"github.com/xtulnx/go-srv/logkit"
+	"google.golang.org/grpc"
+)
+
+// 自动转换错误的拦截器
+//
+//	服务端把 errno 转为 status （ 5xx 同时记录日志，参考 logkit.LogErr ），客户端再还原为 errno
+//
+//	   srv := grpc.NewServer(
+//	       grpc.ChainUnaryInterceptor(grpckit.UnaryServerInterceptor()),
+//	       grpc.ChainStreamInterceptor(grpckit.StreamServerInterceptor()),
+//	   )
+//	   conn, err := grpc.Dial(addr,
+//	       grpc.WithChainUnaryInterceptor(grpckit.UnaryClientInterceptor()),
+//	       grpc.WithChainStreamInterceptor(grpckit.StreamClientInterceptor()),
+//	   )
+
+// UnaryServerInterceptor 服务端：返回的错误转为 status
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, serverErr(ctx, err)
+	}
+}
+
+// StreamServerInterceptor 服务端：返回的错误转为 status
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return serverErr(ss.Context(), handler(srv, ss))
+	}
+}
+
+func serverErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	// 不含 errno 的错误也记录，客户端只能收到 InternalServerError 的描述
+	if _, ok := passThrough(err); !ok {
+		if code, _ := errno.DecodeErr(err); code >= 500 {
+			logkit.LogErr(ctx, err)
+		}
+	}
+	return ToError(err)
+}
+
+// UnaryClientInterceptor 客户端：status 还原为 errno
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor 客户端：建立流与收发消息的 status 还原为 errno ， io.EOF 等原样返回
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromError(err)
+		}
+		return &clientStream{ClientStream: cs}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	return FromError(s.ClientStream.SendMsg(m))
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	return FromError(s.ClientStream.RecvMsg(m))
+}
+
+func (s *clientStream) CloseSend() error {
+	return FromError(s.ClientStream.CloseSend())
+}
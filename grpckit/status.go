@@ -0,0 +1,245 @@
+// Package grpckit gRPC 相关的工具:
+//
+//	errno 与 gRPC status 的转换，以及自动转换的拦截器
+package grpckit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xtulnx/go-srv/errno"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errno 与 gRPC status 的转换
+//
+//	错误码与描述保存在 errdetails.ErrorInfo 中：
+//	  Domain   ErrorDomain
+//	  Reason   错误码
+//	  Metadata message 为描述（消息模板）， param.xxx 为模板的参数
+//	字段校验错误（ errno.MultiErr ）保存在 errdetails.BadRequest 中。
+//	gRPC 的状态码参考 GRPCCode ，没有 ErrorInfo 的 status （如其它服务返回的）按状态码转为 errno
+//
+//	   return nil, grpckit.ToError(errno.QueryNotFound.WithErr(err)) // 服务端，或使用 UnaryServerInterceptor
+//	   err = grpckit.FromError(err)                                   // 客户端，或使用 UnaryClientInterceptor
+//	   errors.Is(err, errno.QueryNotFound)                            // true
+
+// ErrorDomain ErrorInfo 的 Domain ，用于识别 errno 的错误
+const ErrorDomain = "errno"
+
+const paramPrefix = "param."
+
+// GRPCCodes 内置错误码对应的 gRPC 状态码
+var GRPCCodes = map[int]codes.Code{
+	errno.OK.Code:                  codes.OK,
+	errno.BadRequest.Code:          codes.InvalidArgument,
+	errno.Unauthorized.Code:        codes.Unauthenticated,
+	errno.Forbidden.Code:           codes.PermissionDenied,
+	errno.Conflict.Code:            codes.AlreadyExists,
+	errno.TooManyRequests.Code:     codes.ResourceExhausted,
+	errno.InternalServerError.Code: codes.Internal,
+	errno.QueryNotFound.Code:       codes.NotFound,
+	errno.QueryFailed.Code:         codes.Internal,
+	errno.ConvertDataFailed.Code:   codes.Internal,
+	errno.FailedUpdate.Code:        codes.Internal,
+}
+
+// GRPCCode 错误码对应的 gRPC 状态码：先查 GRPCCodes ，再按登记的 HTTP 状态码（ errno.HTTPStatus ）；都没有时为 Unknown
+func GRPCCode(code int) codes.Code {
+	if c, ok := GRPCCodes[code]; ok {
+		return c
+	}
+	if e, ok := errno.Lookup(code); ok && e.HTTPStatus > 0 {
+		return httpToCode(e.HTTPStatus)
+	}
+	return codes.Unknown
+}
+
+func httpToCode(status int) codes.Code {
+	switch status {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if status >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// fromCode 没有 ErrorInfo 时， gRPC 状态码对应的 errno
+func fromCode(c codes.Code) *errno.Errno {
+	switch c {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return errno.BadRequest
+	case codes.Unauthenticated:
+		return errno.Unauthorized
+	case codes.PermissionDenied:
+		return errno.Forbidden
+	case codes.NotFound:
+		return errno.QueryNotFound
+	case codes.AlreadyExists:
+		return errno.Conflict
+	case codes.ResourceExhausted:
+		return errno.TooManyRequests
+	}
+	return errno.InternalServerError
+}
+
+// -o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-o-
+
+// ToStatus 错误转为 gRPC status ：nil 为 OK ；不含 errno 的 status 错误原样返回；
+// context 的取消、超时为 Canceled 、 DeadlineExceeded ；其它按 errno.DecodeErr
+//
+//	不含 errno 的其它错误为 errno.InternalServerError ，原错误的内容不返回给客户端
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if s, ok := passThrough(err); ok {
+		return s
+	}
+	if !errno.IsErrno(err) {
+		err = errno.InternalServerError
+	}
+
+	code, message := errno.DecodeErr(err)
+	info := &errdetails.ErrorInfo{
+		Domain:   ErrorDomain,
+		Reason:   strconv.Itoa(code),
+		Metadata: map[string]string{"message": message},
+	}
+	var e *errno.Err
+	if errors.As(err, &e) && e.Code == code {
+		info.Metadata["message"] = e.Message
+		for k, v := range e.Params {
+			info.Metadata[paramPrefix+k] = fmt.Sprint(v)
+		}
+	}
+	s := status.New(GRPCCode(code), message)
+	if d, err := s.WithDetails(info); err == nil {
+		s = d
+	}
+	var m *errno.MultiErr
+	if errors.As(err, &m) && len(m.Violations) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, v := range m.Violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: v.Field, Description: v.Message})
+		}
+		if d, err := s.WithDetails(br); err == nil {
+			s = d
+		}
+	}
+	return s
+}
+
+// passThrough 不含 errno 的 status 错误、 context 的取消与超时，直接转为 status
+func passThrough(err error) (*status.Status, bool) {
+	if errno.IsErrno(err) {
+		return nil, false
+	}
+	if s, ok := status.FromError(err); ok {
+		return s, true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err), true
+	}
+	return nil, false
+}
+
+// ToError ToStatus 后的错误， nil 时为 nil
+func ToError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return ToStatus(err).Err()
+}
+
+// FromStatus gRPC status 转为 errno ：有 ErrorInfo 时还原错误码、描述与参数，否则按状态码转换；OK 时为 nil
+//
+//	status 的错误保留在错误链中
+func FromStatus(s *status.Status) error {
+	if s == nil || s.Code() == codes.OK {
+		return nil
+	}
+	var info *errdetails.ErrorInfo
+	var br *errdetails.BadRequest
+	for _, d := range s.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Domain == ErrorDomain {
+				info = d
+			}
+		case *errdetails.BadRequest:
+			br = d
+		}
+	}
+
+	if info == nil {
+		return fromCode(s.Code()).WithErr(s.Err())
+	}
+	code, err := strconv.Atoi(info.Reason)
+	if err != nil {
+		return fromCode(s.Code()).WithErr(s.Err())
+	}
+	message, ok := info.Metadata["message"]
+	if !ok {
+		message = s.Message()
+	}
+	if br != nil && len(br.FieldViolations) > 0 {
+		m := errno.Errno{Code: code, Message: message}.Multi().Add(s.Err())
+		for _, v := range br.FieldViolations {
+			m.AddViolation(v.Field, "", v.Description)
+		}
+		return m
+	}
+	e := errno.NewErr(code, message, s.Err())
+	for k, v := range info.Metadata {
+		if strings.HasPrefix(k, paramPrefix) {
+			if e.Params == nil {
+				e.Params = map[string]interface{}{}
+			}
+			e.Params[strings.TrimPrefix(k, paramPrefix)] = v
+		}
+	}
+	return e
+}
+
+// FromError gRPC 的错误转为 errno ，参考 FromStatus ；其它错误原样返回
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errno.IsErrno(err) {
+		return err
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return FromStatus(s)
+}
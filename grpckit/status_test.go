@@ -0,0 +1,127 @@
+package grpckit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/xtulnx/go-srv/errno"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	s := ToStatus(fmt.Errorf("x: %w", errno.QueryNotFound.WithErr(io.EOF)))
+	if s.Code() != codes.NotFound || s.Message() != errno.QueryNotFound.Message {
+		t.Fatalf("ToStatus = %v", s)
+	}
+	err := FromStatus(s)
+	if !errors.Is(err, errno.QueryNotFound) {
+		t.Fatalf("FromStatus = %v", err)
+	}
+
+	// 参数与字段校验错误
+	scope := errno.NewScope("grpckit-test", 91000, 91099)
+	notFound := scope.Define(91001, "用户 {id} 不存在", errno.HTTPStatus(404), errno.Locale("en", "user {id} not found"))
+	err = FromError(ToError(notFound.WithParams(map[string]interface{}{"id": 7})))
+	if _, msg := errno.Localize(err, "en"); msg != "user 7 not found" || status.Code(ToError(err)) != codes.NotFound {
+		t.Fatalf("params = %v", err)
+	}
+	err = FromError(ToError(errno.BadRequest.Multi().AddViolation("name", "required", "不能为空")))
+	var m *errno.MultiErr
+	if !errors.As(err, &m) || len(m.Violations) != 1 || m.Violations[0].Field != "name" {
+		t.Fatalf("violations = %v", err)
+	}
+
+	// 其它服务的 status
+	if err := FromError(status.Error(codes.PermissionDenied, "denied")); !errors.Is(err, errno.Forbidden) {
+		t.Fatalf("plain status = %v", err)
+	}
+	if s := ToStatus(status.Error(codes.Unavailable, "x")); s.Code() != codes.Unavailable {
+		t.Fatalf("passthrough = %v", s)
+	}
+	// 其它错误不返回原错误的内容
+	s = ToStatus(errors.New("dial tcp 10.0.0.1:3306: secret"))
+	if s.Code() != codes.Internal || s.Message() != errno.InternalServerError.Message {
+		t.Fatalf("plain error = %v", s)
+	}
+	if err := FromStatus(s); !errors.Is(err, errno.InternalServerError) {
+		t.Fatalf("plain error = %v", err)
+	}
+	for _, d := range s.Details() {
+		if strings.Contains(fmt.Sprint(d), "secret") {
+			t.Fatalf("details = %v", d)
+		}
+	}
+	if FromError(io.EOF) != io.EOF || ToError(nil) != nil {
+		t.Fatal("nil / other")
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryServerInterceptor()))
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Svc",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Get",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/test.Svc/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, errno.QueryNotFound.WithErr(io.EOF, "id", 1)
+				})
+			},
+		}, {
+			MethodName: "Fail",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/test.Svc/Fail"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, errors.New("secret")
+				})
+			},
+		}},
+	}, struct{}{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = conn.Invoke(context.Background(), "/test.Svc/Get", &emptypb.Empty{}, &emptypb.Empty{})
+	if code, msg := errno.DecodeErr(err); !errors.Is(err, errno.QueryNotFound) || code != errno.QueryNotFound.Code || msg != errno.QueryNotFound.Message {
+		t.Fatalf("Invoke = %v", err)
+	}
+
+	// 不含 errno 的错误记录日志，客户端只收到 InternalServerError 的描述
+	hook := test.NewGlobal()
+	err = conn.Invoke(context.Background(), "/test.Svc/Fail", &emptypb.Empty{}, &emptypb.Empty{})
+	if code, msg := errno.DecodeErr(err); code != errno.InternalServerError.Code || msg != errno.InternalServerError.Message {
+		t.Fatalf("Invoke = %v", err)
+	}
+	if e := hook.LastEntry(); e == nil || e.Level != logrus.ErrorLevel || fmt.Sprint(e.Data[logrus.ErrorKey]) != "secret" {
+		t.Fatalf("log = %+v", e)
+	}
+}
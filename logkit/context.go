@@ -0,0 +1,43 @@
+package logkit
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// 请求/链路 ID 随 context 传递
+
+type traceIDKey struct{}
+
+// TraceIDKeys 从 context 中查找 ID 时使用的字符串键，兼容 gin.Context 的 c.Set
+var TraceIDKeys = []string{"trace_id", "request_id", "X-Request-Id"}
+
+// WithTraceID 在 context 中保存 ID
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceID 从 context 中取出 ID ，没有时返回空
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+		return id
+	}
+	for _, k := range TraceIDKeys {
+		if id, ok := ctx.Value(k).(string); ok && id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// ForContext 添加 trace_id 字段
+func ForContext(ctx context.Context) *logrus.Entry {
+	e := std.WithContext(ctx)
+	if id := TraceID(ctx); id != "" {
+		e = e.WithField("trace_id", id)
+	}
+	return e
+}
@@ -0,0 +1,49 @@
+package logkit
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/xtulnx/go-srv/errno"
+)
+
+// 记录 errno 错误
+//
+//	5xx 以 Error 记录，附带详细字段（ errno.FieldsOf ）与调用栈（ errno.StackOf ）；
+//	其它错误码视为客户端的问题，以 Info 记录错误码与描述
+//
+//	   logkit.LogErr(c, err) // c 为 *gin.Context 或 context.Context
+
+// ErrFields 错误的日志字段：错误码、详细字段；withStack 时包括调用栈
+func ErrFields(err error, withStack bool) Fields {
+	fields := Fields{}
+	for k, v := range errno.FieldsOf(err) {
+		fields[k] = v
+	}
+	fields["code"], _ = errno.DecodeErr(err)
+	if withStack {
+		if st := errno.StackOf(err); len(st) > 0 {
+			fields["stack"] = fmt.Sprintf("%+v", st)
+		}
+	}
+	return fields
+}
+
+// WithErr 添加错误相关的字段，参考 ErrFields
+func WithErr(entry *logrus.Entry, err error) *logrus.Entry {
+	code, _ := errno.DecodeErr(err)
+	return entry.WithFields(ErrFields(err, code >= 500)).WithError(err)
+}
+
+// LogErr 记录错误， err 为 nil 时忽略
+func LogErr(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	entry := WithErr(ForContext(ctx), err)
+	if code, _ := errno.DecodeErr(err); code >= 500 {
+		entry.Error("request failed")
+	} else {
+		entry.Info("request failed")
+	}
+}